@@ -0,0 +1,193 @@
+// Package cmdutil handles the results of the provider subcommands the same
+// way for both: recording the proxy they went through, checking them against
+// thresholds, writing them out in a machine readable format and pushing them
+// to sinks.
+package cmdutil
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"framey/assignment/cmd/internal/exitcode"
+	"framey/assignment/internal/report"
+	"framey/assignment/internal/sink"
+	"framey/assignment/internal/units"
+	"framey/assignment/pkg/retry"
+	"framey/assignment/pkg/transport"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Flags are the options about results shared by the provider subcommands.
+type Flags struct {
+	Format      string
+	Output      string
+	Append      bool
+	CSVHeader   bool
+	Influx      stringList
+	InfluxToken string
+
+	MinDownload units.BitsPerSecond
+	MinUpload   units.BitsPerSecond
+	MaxLatency  time.Duration
+}
+
+// Register defines the flags on fs and returns where they are stored.
+func Register(fs *flag.FlagSet) *Flags {
+	f := new(Flags)
+	fs.StringVar(&f.Format, "format", "text", "Output format: text, or json or csv for a single machine readable record")
+	fs.StringVar(&f.Output, "output", "", "File to write json or csv results to instead of standard output")
+	fs.BoolVar(&f.Append, "append", false, "Append to the -output file instead of replacing it; a CSV header is only written to empty files")
+	fs.BoolVar(&f.CSVHeader, "csv-header", false, "Print the CSV header line and exit")
+	fs.Var(&f.Influx, "influx", "Push results in InfluxDB line protocol to -, a file or an http(s) write endpoint URL; may be repeated")
+	fs.StringVar(&f.InfluxToken, "influx.token", "", "Token authorising writes to the InfluxDB endpoints (default $INFLUX_TOKEN)")
+	fs.Var(&f.MinDownload, "min-download", "Exit with status 3 if the download speed is below this, e.g. 100Mbps or 12.5MB/s")
+	fs.Var(&f.MinUpload, "min-upload", "Exit with status 3 if the upload speed is below this, e.g. 10Mbps")
+	fs.DurationVar(&f.MaxLatency, "max-latency", 0, "Exit with status 3 if the latency is above this, e.g. 25ms")
+	return f
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// Check validates the flags once parsed and reports whether progress is
+// printed as text. It exits on invalid flags, and after printing the CSV
+// header if asked to.
+func (f *Flags) Check() (text bool) {
+	switch f.Format {
+	case "text":
+		if f.Output != "" {
+			exitcode.Fatalf(exitcode.Usage, "-output requires -format=json or -format=csv")
+		}
+		text = true
+	case report.JSON, report.CSV:
+		if f.Output == "" {
+			for _, dest := range f.Influx {
+				if dest == "-" {
+					exitcode.Fatalf(exitcode.Usage, "-influx=- requires -output with -format=json or -format=csv, which would share standard output")
+				}
+			}
+		}
+	default:
+		exitcode.Fatalf(exitcode.Usage, "Unknown output format: %q", f.Format)
+	}
+	if f.CSVHeader {
+		if err := report.WriteCSVHeader(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(exitcode.OK)
+	}
+	return text
+}
+
+// Thresholds returns the thresholds selected by the flags.
+func (f *Flags) Thresholds() report.Thresholds {
+	return report.Thresholds{MinDownload: f.MinDownload, MinUpload: f.MinUpload, MaxLatency: f.MaxLatency}
+}
+
+// Finish reports res, from a run that ended with err, and exits with the
+// status it calls for: it checks res against the thresholds, pushes it to the
+// sinks, and writes it out unless the output is text.
+func (f *Flags) Finish(res *report.Result, err error, p retry.Policy) {
+	res.Fail(err)
+	f.Thresholds().Check(res)
+	f.Push(res, p)
+	if f.Format != "text" {
+		if err := f.Write(res); err != nil {
+			log.Fatalf("Error writing result: %v", err)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(res.Breaches) > 0 {
+		for _, b := range res.Breaches {
+			log.Printf("Threshold breached: %s", b)
+		}
+		os.Exit(exitcode.Threshold)
+	}
+}
+
+// Runner returns a function running tests against provider with run, for the
+// long running subcommands, which check and push the results the same way as
+// Finish but keep them.
+func (f *Flags) Runner(
+	provider string,
+	p retry.Policy,
+	run func(ctx context.Context, res *report.Result) error,
+) func(ctx context.Context) *report.Result {
+	return func(ctx context.Context) *report.Result {
+		res := report.New(provider)
+		res.Fail(run(ctx, res))
+		f.Thresholds().Check(res)
+		// An aborted test says nothing about the network.
+		if ctx.Err() == nil {
+			f.Push(res, p)
+		}
+		return res
+	}
+}
+
+// Write writes res to the output selected by the flags.
+func (f *Flags) Write(res *report.Result) error {
+	w, empty, err := report.Open(f.Output, f.Append)
+	if err != nil {
+		return err
+	}
+	if err := res.Write(w, f.Format, empty); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Proxy returns the proxy that requests to url go through with opts, with any
+// password redacted, for the result. The proxy changes what is being
+// measured, so it is also told on out up front.
+func Proxy(out io.Writer, opts transport.Options, url string) (string, error) {
+	p, err := opts.ProxyFor(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure proxy: %v", err)
+	}
+	if p != "" {
+		fmt.Fprintf(out, "Testing through proxy %s\n", p)
+	}
+	return p, nil
+}
+
+// How long pushing a result to the sinks may take, retries included.
+const pushTimeout = 30 * time.Second
+
+// Push pushes res to the sinks selected by the flags, retrying according to
+// p. Failing to is not worth losing the result over.
+func (f *Flags) Push(res *report.Result, p retry.Policy) {
+	if len(f.Influx) == 0 {
+		return
+	}
+	token := f.InfluxToken
+	if token == "" {
+		token = os.Getenv("INFLUX_TOKEN")
+	}
+	sinks := make(sink.Multi, len(f.Influx))
+	for i, dest := range f.Influx {
+		sinks[i] = sink.Influx(dest, token)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	ctx = retry.WithPolicy(ctx, p)
+	if err := sinks.Send(ctx, res); err != nil {
+		log.Printf("Error pushing result: %v", err)
+	}
+}
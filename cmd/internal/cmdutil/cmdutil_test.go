@@ -0,0 +1,82 @@
+package cmdutil
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"framey/assignment/internal/report"
+	"framey/assignment/pkg/retry"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlags_Runner(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.lp"), filepath.Join(dir, "b.lp")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := Register(fs)
+	if err := fs.Parse([]string{"-influx", a, "-influx", b, "-min-download", "100Mbps"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	run := f.Runner(report.Fast, retry.Never, func(ctx context.Context, res *report.Result) error {
+		res.Download = &report.Transfer{BitsPerSecond: 50e6}
+		res.Upload = &report.Transfer{BitsPerSecond: 50e6}
+		res.Latency = &report.Latency{Ms: 10}
+		return nil
+	})
+	res := run(context.Background())
+	if res.Provider != report.Fast || len(res.Breaches) != 1 {
+		t.Errorf("got: %+v", res)
+	}
+
+	// Every sink got the result.
+	for _, path := range []string{a, b} {
+		lp, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(string(lp), "download_bps=50000000") {
+			t.Errorf("%s: got %q", path, lp)
+		}
+	}
+}
+
+func TestFlags_RunnerAborted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.lp")
+	f := &Flags{Influx: stringList{path}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := f.Runner(report.Speedtest, retry.Never, func(ctx context.Context, res *report.Result) error {
+		return errors.New("aborted")
+	})(ctx)
+	if res.Error != "aborted" {
+		t.Errorf("got: %+v", res)
+	}
+	if _, err := ioutil.ReadFile(path); err == nil {
+		t.Errorf("An aborted test was pushed")
+	}
+}
+
+func TestFlags_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	f := &Flags{Format: report.CSV, Output: path, Append: true}
+	for i := 0; i < 2; i++ {
+		if err := f.Write(report.New(report.Fast)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A header, then a record per result.
+	if lines := strings.Count(string(b), "\n"); lines != 3 {
+		t.Errorf("got %d lines: %q", lines, b)
+	}
+}
@@ -0,0 +1,261 @@
+// Package daemon implements the subcommand running tests on a schedule and
+// keeping their results.
+package daemon
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"framey/assignment/cmd/internal/exitcode"
+	"framey/assignment/cmd/internal/provider"
+	"framey/assignment/internal/cron"
+	"framey/assignment/internal/history"
+	"framey/assignment/internal/report"
+	"framey/assignment/internal/sink"
+	"framey/assignment/internal/units"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	flagSet      = flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval     = flagSet.Duration("interval", time.Hour, "Time between the starts of tests")
+	cronExpr     = flagSet.String("cron", "", "Cron expression scheduling tests, e.g. \"*/30 * * * *\", instead of -interval")
+	jitter       = flagSet.Duration("jitter", time.Minute, "Maximum random delay added to every scheduled test, so that fleets don't test in sync")
+	providerName = flagSet.String("provider", "alternate", "Provider to test: speedtest.net, fast.com, or alternate to take turns")
+	stArgs       = flagSet.String("args.speedtest", "", "Options for speedtest.net tests, as given to its subcommand, separated by spaces")
+	fastArgs     = flagSet.String("args.fast", "", "Options for fast.com tests, as given to its subcommand, separated by spaces")
+	historyPath  = flagSet.String("history", defaultHistoryPath(), "File to append the result of every test to")
+	shutdown     = flagSet.String("shutdown", "finish", "What to do with the current test on SIGTERM: finish or abort it. A second signal always aborts")
+
+	webhook       = flagSet.String("webhook", "", "URL to post an alert to when a test fails or breaches the thresholds in its provider options, and when it recovers")
+	webhookTmpl   = flagSet.String("webhook.template", "json", "Alert payload: json, slack, teams, or the path to a text/template file executed with the alert")
+	webhookRepeat = flagSet.Duration("webhook.repeat", 6*time.Hour, "Time after which a persisting problem is alerted again, or 0 to alert it only once")
+)
+
+func defaultHistoryPath() string {
+	p, err := history.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+type runner struct {
+	name string
+	run  func(ctx context.Context) *report.Result
+}
+
+func Main(args []string) {
+	if err := flagSet.Parse(args[1:]); err != nil {
+		panic(err)
+	}
+	next, err := schedule(*cronExpr, *interval, time.Now)
+	if err != nil {
+		exitcode.Fatalf(exitcode.Usage, "%v", err)
+	}
+	runners, err := providers()
+	if err != nil {
+		exitcode.Fatalf(exitcode.Usage, "%v", err)
+	}
+	if *historyPath == "" {
+		exitcode.Fatalf(exitcode.Usage, "-history is required where there is no configuration directory")
+	}
+	if *shutdown != "finish" && *shutdown != "abort" {
+		exitcode.Fatalf(exitcode.Usage, "Unknown -shutdown behaviour: %q", *shutdown)
+	}
+	hook, err := newWebhook()
+	if err != nil {
+		exitcode.Fatalf(exitcode.Usage, "%v", err)
+	}
+	store := history.Store{Path: *historyPath}
+
+	// Canceling ctx aborts the current test, closing stop ends the loop.
+	ctx, abort := context.WithCancel(context.Background())
+	defer abort()
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	go handleSignals(sigs, stop, abort, *shutdown == "abort")
+
+	err = loop(ctx, stop, runners, next, *jitter, func(res *report.Result) {
+		logResult(res)
+		if err := store.Append(res); err != nil {
+			log.Printf("Error recording result: %v", err)
+		}
+		if hook != nil {
+			alert(hook, res)
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Stops the loop on the first signal, also aborting the current test if
+// abortNow is set, and aborts it on the second.
+func handleSignals(sigs <-chan os.Signal, stop chan<- struct{}, abort func(), abortNow bool) {
+	s := <-sigs
+	log.Printf("Received %v, stopping", s)
+	close(stop)
+	if abortNow {
+		abort()
+	}
+	<-sigs
+	abort()
+}
+
+// Runs tests with runners in turn at the times given by next, delayed by up
+// to jitter, and passes every completed result to done, until stop is closed.
+// A test running then is finished unless ctx is canceled, in which case it is
+// left out. It only fails if the schedule runs out.
+func loop(
+	ctx context.Context,
+	stop <-chan struct{},
+	runners []runner,
+	next func(last time.Time) time.Time,
+	jitter time.Duration,
+	done func(res *report.Result),
+) error {
+	// The slot of the last test, before jitter, which mustn't carry over to
+	// the following slots.
+	var slot time.Time
+	for i := 0; ; i++ {
+		r := runners[i%len(runners)]
+		slot = next(slot)
+		if slot.IsZero() {
+			return fmt.Errorf("the schedule has no more tests")
+		}
+		at := slot
+		if jitter > 0 {
+			at = at.Add(time.Duration(rand.Int63n(int64(jitter))))
+		}
+		log.Printf("Next %s test at %v", r.name, at.Format(time.RFC3339))
+
+		t := time.NewTimer(time.Until(at))
+		select {
+		case <-t.C:
+		case <-stop:
+			t.Stop()
+			return nil
+		}
+
+		res := r.run(ctx)
+		if ctx.Err() != nil {
+			log.Printf("Aborted the %s test", r.name)
+			return nil
+		}
+		done(res)
+
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+	}
+}
+
+// Returns a function giving the time of the test following one scheduled at
+// last, or the first one if last is zero, by the cron expression if given or
+// every interval otherwise. now tells the time.
+func schedule(cronExpr string, interval time.Duration, now func() time.Time) (func(last time.Time) time.Time, error) {
+	if cronExpr != "" {
+		s, err := cron.Parse(cronExpr)
+		if err != nil {
+			return nil, err
+		}
+		return func(time.Time) time.Time {
+			return s.Next(now())
+		}, nil
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("-interval must be positive")
+	}
+	return func(last time.Time) time.Time {
+		now := now()
+		if last.IsZero() {
+			return now
+		}
+		// Skip the slots missed by a test running for longer than the
+		// interval rather than catching up on them.
+		next := last.Add(interval)
+		for next.Before(now) {
+			next = next.Add(interval)
+		}
+		return next
+	}, nil
+}
+
+// Returns the providers to take turns with.
+func providers() ([]runner, error) {
+	names := []string{report.Speedtest, report.Fast}
+	if *providerName != "alternate" {
+		name, err := provider.Canonical(*providerName)
+		if err != nil {
+			return nil, err
+		}
+		names = []string{name}
+	}
+
+	args := map[string]string{report.Speedtest: *stArgs, report.Fast: *fastArgs}
+	runners := make([]runner, len(names))
+	for i, name := range names {
+		run, err := provider.Runner(name, strings.Fields(args[name]))
+		if err != nil {
+			return nil, err
+		}
+		runners[i] = runner{name, run}
+	}
+	return runners, nil
+}
+
+// Returns the webhook selected by the flags, or nil for none.
+func newWebhook() (*sink.Webhook, error) {
+	if *webhook == "" {
+		return nil, nil
+	}
+	tmpl, err := sink.WebhookTemplate(*webhookTmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &sink.Webhook{URL: *webhook, Template: tmpl, Repeat: *webhookRepeat}, nil
+}
+
+// How long posting an alert may take, retries included.
+const alertTimeout = 30 * time.Second
+
+// Posts res to the webhook if it needs attention. A failed post is tried
+// again after the next test.
+func alert(hook *sink.Webhook, res *report.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	if err := hook.Send(ctx, res); err != nil {
+		log.Printf("Error posting alert: %v", err)
+	}
+}
+
+func logResult(res *report.Result) {
+	if res.Error != "" {
+		log.Printf("The %s test failed: %s", res.Provider, res.Error)
+		return
+	}
+	var parts []string
+	if res.Download != nil {
+		parts = append(parts, fmt.Sprintf("download %v", units.BitsPerSecond(res.Download.BitsPerSecond)))
+	}
+	if res.Upload != nil {
+		parts = append(parts, fmt.Sprintf("upload %v", units.BitsPerSecond(res.Upload.BitsPerSecond)))
+	}
+	if res.Latency != nil {
+		parts = append(parts, fmt.Sprintf("latency %.1f ms", res.Latency.Ms))
+	}
+	log.Printf("%s: %s", res.Provider, strings.Join(parts, ", "))
+	for _, b := range res.Breaches {
+		log.Printf("%s: threshold breached: %s", res.Provider, b)
+	}
+}
@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"context"
+	"framey/assignment/internal/report"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSchedule_Interval(t *testing.T) {
+	now := start
+	next, err := schedule("", time.Hour, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first := next(time.Time{})
+	if !first.Equal(start) {
+		t.Errorf("got: %v", first)
+	}
+
+	// A short test leaves the following slot on time.
+	now = start.Add(10 * time.Minute)
+	if got := next(first); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("got: %v", got)
+	}
+
+	// A test running past the following slots skips them.
+	now = start.Add(150 * time.Minute)
+	if got := next(first); !got.Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("got: %v", got)
+	}
+}
+
+func TestSchedule_Cron(t *testing.T) {
+	now := start.Add(10 * time.Minute)
+	next, err := schedule("*/30 * * * *", 0, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := next(time.Time{}); !got.Equal(start.Add(30 * time.Minute)) {
+		t.Errorf("got: %v", got)
+	}
+	now = start.Add(45 * time.Minute)
+	if got := next(start.Add(30 * time.Minute)); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("got: %v", got)
+	}
+}
+
+func TestSchedule_Invalid(t *testing.T) {
+	if _, err := schedule("", 0, time.Now); err == nil {
+		t.Errorf("Expected an error for a zero interval")
+	}
+	if _, err := schedule("not cron", time.Hour, time.Now); err == nil {
+		t.Errorf("Expected an error for an invalid cron expression")
+	}
+}
+
+func testRunners(names ...string) []runner {
+	var rs []runner
+	for _, name := range names {
+		name := name
+		rs = append(rs, runner{name: name, run: func(context.Context) *report.Result {
+			return report.New(name)
+		}})
+	}
+	return rs
+}
+
+func TestLoop_Jitter(t *testing.T) {
+	// Slots in the past, so that tests start right away.
+	var slots, lasts []time.Time
+	next := func(last time.Time) time.Time {
+		lasts = append(lasts, last)
+		slot := start.Add(time.Duration(len(slots)) * time.Hour)
+		slots = append(slots, slot)
+		return slot
+	}
+
+	stop := make(chan struct{})
+	var got []string
+	done := func(res *report.Result) {
+		got = append(got, res.Provider)
+		if len(got) == 3 {
+			close(stop)
+		}
+	}
+	err := loop(context.Background(), stop, testRunners(report.Speedtest, report.Fast), next, time.Millisecond, done)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(got) != 3 || got[0] != report.Speedtest || got[1] != report.Fast || got[2] != report.Speedtest {
+		t.Errorf("got: %v", got)
+	}
+	// Every slot follows the previous one, not the jittered time it ran at.
+	if !lasts[0].IsZero() {
+		t.Errorf("got: %v", lasts[0])
+	}
+	for i := 1; i < len(lasts); i++ {
+		if !lasts[i].Equal(slots[i-1]) {
+			t.Errorf("Slot %d followed %v, expected %v", i, lasts[i], slots[i-1])
+		}
+	}
+}
+
+func TestLoop_NoMoreTests(t *testing.T) {
+	next := func(time.Time) time.Time { return time.Time{} }
+	err := loop(context.Background(), make(chan struct{}), testRunners(report.Fast), next, 0, func(*report.Result) {
+		t.Errorf("Unexpected result")
+	})
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+// Runs a loop whose single test gets a signal while running, and returns the
+// results recorded.
+func runSignaled(t *testing.T, abortNow bool, signals int) []*report.Result {
+	ctx, abort := context.WithCancel(context.Background())
+	defer abort()
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 2)
+	go handleSignals(sigs, stop, abort, abortNow)
+
+	runners := []runner{{name: report.Fast, run: func(ctx context.Context) *report.Result {
+		for i := 0; i < signals; i++ {
+			sigs <- syscall.SIGTERM
+		}
+		// Wait for the signals to take effect.
+		<-stop
+		if abortNow || signals > 1 {
+			<-ctx.Done()
+		}
+		return report.New(report.Fast)
+	}}}
+	next := func(time.Time) time.Time { return start }
+
+	var results []*report.Result
+	err := loop(ctx, stop, runners, next, 0, func(res *report.Result) {
+		results = append(results, res)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return results
+}
+
+func TestLoop_ShutdownFinish(t *testing.T) {
+	if results := runSignaled(t, false, 1); len(results) != 1 {
+		t.Errorf("Recorded %d results, expected 1", len(results))
+	}
+}
+
+func TestLoop_ShutdownAbort(t *testing.T) {
+	if results := runSignaled(t, true, 1); len(results) != 0 {
+		t.Errorf("Recorded %d results, expected none", len(results))
+	}
+}
+
+func TestLoop_SecondSignal(t *testing.T) {
+	if results := runSignaled(t, false, 2); len(results) != 0 {
+		t.Errorf("Recorded %d results, expected none", len(results))
+	}
+}
+
+func TestLogResult_Partial(t *testing.T) {
+	// A test of only the latency mustn't crash.
+	res := report.New(report.Speedtest)
+	res.Latency = &report.Latency{Ms: 10}
+	logResult(res)
+}
@@ -0,0 +1,25 @@
+// Package exitcode defines the exit statuses of the command, so that scripts
+// can tell a slow network from a broken test.
+package exitcode
+
+import (
+	"log"
+	"os"
+)
+
+const (
+	// OK is a completed test within all the thresholds.
+	OK = 0
+	// Error is a test that couldn't be completed, or a failure to report it.
+	Error = 1
+	// Usage is invalid options, matching the flag package.
+	Usage = 2
+	// Threshold is a completed test that breached a threshold.
+	Threshold = 3
+)
+
+// Fatalf logs like log.Printf and exits with code.
+func Fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(code)
+}
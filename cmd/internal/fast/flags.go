@@ -2,16 +2,60 @@ package fast
 
 import (
 	"flag"
+	"framey/assignment/cmd/internal/cmdutil"
+	"framey/assignment/internal/diskcache"
+	fast2 "framey/assignment/pkg/fast"
+	"framey/assignment/pkg/retry"
+	"os"
 	"time"
 )
 
 var (
 	flagSet = flag.NewFlagSet("fast", flag.ExitOnError)
+	results = cmdutil.Register(flagSet)
 
 	fmtBytes = flagSet.Bool("bytes", false, "Display speeds in SI bytes (default is bits)")
+	verbose  = flagSet.Bool("v", false, "Print a per-phase breakdown of request timings, which machine readable results always include")
 	urlCount = flagSet.Int("urls", 5, "Number of URLs to use to probe")
+	dropSlow = flagSet.Int("drop_slowest", 0, "Number of highest latency URLs to skip when probing speed")
+	source   = flagSet.String("source", "", "Local IP address or network interface to test from")
+	proxy    = flagSet.String("proxy", "", "Proxy URL (http://, https:// or socks5://, optionally with user:password@)")
+	retries  = flagSet.Int("retries", retry.DefaultPolicy.Attempts-1, "Number of times to retry failed discovery requests")
 	cfgTime  = flagSet.Duration("time.config", 10*time.Second, "Timeout for getting initial configuration")
 	pngTime  = flagSet.Duration("time.latency", 1*time.Second, "Timeout for latency detection phase")
 	dlTime   = flagSet.Duration("time.download", 10*time.Second, "Maximum time to spend in download probe phase")
 	ulTime   = flagSet.Duration("time.upload", 10*time.Second, "Maximum time to spend in upload probe phase")
+
+	token       = flagSet.String("token", "", "fast.com API token to try before scraping one (default $FAST_TOKEN)")
+	cacheDir    = flagSet.String("cache.dir", defaultCacheDir(), "Directory to keep the fast.com token in")
+	cacheBypass = flagSet.Bool("cache.bypass", false, "Scrape a new fast.com token instead of reusing the kept one")
 )
+
+// Returns the retry policy for discovery requests selected by the flags.
+func retryPolicy() retry.Policy {
+	p := retry.DefaultPolicy
+	p.Attempts = *retries + 1
+	return p
+}
+
+func defaultCacheDir() string {
+	d, err := diskcache.DefaultDir()
+	if err != nil {
+		return ""
+	}
+	return string(d)
+}
+
+// Returns the token store selected by the flags, or nil to bypass it.
+func tokenStore() fast2.TokenStore {
+	if *token != "" {
+		return fast2.StaticToken(*token)
+	}
+	if t := os.Getenv("FAST_TOKEN"); t != "" {
+		return fast2.StaticToken(t)
+	}
+	if *cacheBypass || *cacheDir == "" {
+		return nil
+	}
+	return fast2.FileTokenStore{Dir: *cacheDir}
+}
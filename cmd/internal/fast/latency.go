@@ -0,0 +1,51 @@
+package fast
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/internal/report"
+	fast2 "framey/assignment/pkg/fast"
+	"time"
+)
+
+// Ranks the servers by latency, drops the slowest ones if asked to and prints
+// the unloaded latency, which is that of the best server.
+func measureLatency(ctx context.Context, m *fast2.Manifest, client *fast2.Client) ([]report.Target, *report.Latency, error) {
+	ctx, cancel := context.WithTimeout(ctx, *pngTime)
+	defer cancel()
+
+	latencies, err := m.SortTargetsByAverageLatency(ctx, client, fast2.DefaultLatencySamples)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get server latencies: %v", err)
+	}
+
+	targets := m.Targets()
+	if *dropSlow > 0 {
+		m.KeepTargets(len(targets) - *dropSlow)
+	}
+	kept := len(m.Targets())
+
+	reported := make([]report.Target, len(targets))
+	for i, t := range targets {
+		var skipped string
+		if i >= kept {
+			skipped = " (skipped)"
+		}
+		fmt.Fprintf(out, "Using server %s (%v): %.1f ms%s\n",
+			t.Name, t.Location, ms(latencies[t.URL]), skipped)
+		reported[i] = report.Target{
+			Name:      t.Name,
+			URL:       t.URL,
+			Location:  t.Location.String(),
+			LatencyMs: ms(latencies[t.URL]),
+			Skipped:   i >= kept,
+		}
+	}
+	unloaded := ms(latencies[targets[0].URL])
+	fmt.Fprintf(out, "Unloaded latency: %.1f ms\n", unloaded)
+	return reported, &report.Latency{Ms: unloaded}, nil
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -2,26 +2,105 @@ package fast
 
 import (
 	"context"
+	"fmt"
+	"framey/assignment/cmd/internal/cmdutil"
+	"framey/assignment/internal/report"
 	fast2 "framey/assignment/pkg/fast"
-	"log"
+	"framey/assignment/pkg/retry"
+	"framey/assignment/pkg/transport"
+	"io"
+	"io/ioutil"
+	"os"
 )
 
+// Where human readable progress goes, nowhere unless the output format is
+// text.
+var out io.Writer = os.Stdout
+
 func Main(args []string) {
 	err := flagSet.Parse(args[1:])
 	if err != nil {
 		panic(err)
 	}
+	if !results.Check() {
+		out = ioutil.Discard
+	}
+
+	res := report.New(report.Fast)
+	results.Finish(res, run(context.Background(), res), retryPolicy())
+}
+
+func textOutput() bool {
+	return out != ioutil.Discard
+}
+
+// Runner parses the flags in args and returns a function running a test with
+// them, for the long running subcommands. Nothing is printed.
+func Runner(args []string) func(ctx context.Context) *report.Result {
+	if err := flagSet.Parse(args); err != nil {
+		panic(err)
+	}
+	out = ioutil.Discard
+	return results.Runner(report.Fast, retryPolicy(), run)
+}
 
-	var client fast2.Client
+// Runs the test, filling res in as it goes, until done or ctx is canceled.
+func run(ctx context.Context, res *report.Result) error {
+	client, proxy, err := newClient()
+	if err != nil {
+		return err
+	}
+	res.Proxy = proxy
 
-	ctx, cancel := context.WithTimeout(context.Background(), *cfgTime)
+	cfgCtx, cancel := context.WithTimeout(ctx, *cfgTime)
 	defer cancel()
+	cfgCtx = retry.WithPolicy(cfgCtx, retryPolicy())
 
-	m, err := fast2.GetManifest(ctx, *urlCount)
+	m, err := client.GetManifestWithStore(cfgCtx, *urlCount, tokenStore())
 	if err != nil {
-		log.Fatalf("Error loading fast.com configuration: %v", err)
+		return fmt.Errorf("failed to load fast.com configuration: %v", err)
+	}
+	printClient(m)
+	res.Client = clientReport(m.Client())
+
+	if res.Targets, res.Latency, err = measureLatency(ctx, m, client); err != nil {
+		return err
 	}
 
-	download(m, &client)
-	upload(m, &client)
+	if res.Download, err = download(ctx, m, client); err != nil {
+		return err
+	}
+	res.Upload, err = upload(ctx, m, client)
+	return err
+}
+
+// Returns a client configured by the flags, after telling about any proxy,
+// along with the proxy with any password redacted.
+func newClient() (*fast2.Client, string, error) {
+	opts := transport.Options{Source: *source, Proxy: *proxy}
+	client, err := fast2.NewClient(opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to configure client: %v", err)
+	}
+
+	p, err := cmdutil.Proxy(out, opts, "https://fast.com/")
+	if err != nil {
+		return nil, "", err
+	}
+	return client, p, nil
+}
+
+// Prints the client much like the speedtest.net subcommand does.
+func printClient(m *fast2.Manifest) {
+	c := m.Client()
+	fmt.Fprintf(out, "Testing from %s (%s, AS%s) in %v...\n", c.ISP, c.IP, c.ASN, c.Location)
+}
+
+func clientReport(c fast2.ClientInfo) report.Client {
+	return report.Client{
+		IP:       c.IP,
+		ISP:      c.ISP,
+		ASN:      c.ASN,
+		Location: c.Location.String(),
+	}
 }
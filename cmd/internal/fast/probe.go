@@ -4,47 +4,78 @@ import (
 	"context"
 	"fmt"
 	"framey/assignment/internal/oututil"
+	"framey/assignment/internal/report"
 	"framey/assignment/internal/units"
 	fast2 "framey/assignment/pkg/fast"
-	"log"
+	"framey/assignment/pkg/timing"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
-func download(m *fast2.Manifest, client *fast2.Client) {
-	ctx, cancel := context.WithTimeout(context.Background(), *dlTime)
+func download(ctx context.Context, m *fast2.Manifest, client *fast2.Client) (*report.Transfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, *dlTime)
 	defer cancel()
+	ctx, timings := traceTimings(ctx)
 
 	stream, finalize := proberPrinter(func(s units.BytesPerSecond) string {
 		return formatSpeed("Download speed", s)
 	})
-	speed, err := m.ProbeDownloadSpeed(ctx, client, stream)
+	start := time.Now()
+	t, err := m.ProbeDownload(ctx, client, stream)
 	if err != nil {
-		log.Fatalf("Error probing download speed: %v", err)
-		return
+		return nil, fmt.Errorf("failed to probe download speed: %v", err)
 	}
-	finalize(speed)
+	d := time.Since(start)
+	finalize(t.Speed)
+	printTimings(timings)
+	return report.NewTransfer(t.Speed, t.Bytes, d, timings), nil
 }
 
-func upload(m *fast2.Manifest, client *fast2.Client) {
-	ctx, cancel := context.WithTimeout(context.Background(), *ulTime)
+func upload(ctx context.Context, m *fast2.Manifest, client *fast2.Client) (*report.Transfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, *ulTime)
 	defer cancel()
+	ctx, timings := traceTimings(ctx)
 
 	stream, finalize := proberPrinter(func(s units.BytesPerSecond) string {
 		return formatSpeed("Upload speed", s)
 	})
-	speed, err := m.ProbeUploadSpeed(ctx, client, stream)
+	start := time.Now()
+	t, err := m.ProbeUpload(ctx, client, stream)
 	if err != nil {
-		log.Fatalf("Error probing upload speed: %v", err)
-		return
+		return nil, fmt.Errorf("failed to probe upload speed: %v", err)
 	}
-	finalize(speed)
+	d := time.Since(start)
+	finalize(t.Speed)
+	printTimings(timings)
+	return report.NewTransfer(t.Speed, t.Bytes, d, timings), nil
 }
 
+// Attaches a timing recorder to ctx, for the result.
+func traceTimings(ctx context.Context) (context.Context, *timing.Recorder) {
+	r := new(timing.Recorder)
+	return timing.WithRecorder(ctx, r), r
+}
+
+// Prints timings when running verbosely, unless the output format is machine
+// readable.
+func printTimings(r *timing.Recorder) {
+	if *verbose && textOutput() {
+		oututil.PrintTimings(r)
+	}
+}
+
+// Returns a stream of speeds to print progress from and a function printing
+// the final speed, or a nil stream and a no-op unless the output format is
+// text.
 func proberPrinter(format func(units.BytesPerSecond) string) (
 	stream chan units.BytesPerSecond,
 	finalize func(units.BytesPerSecond),
 ) {
+	if !textOutput() {
+		return nil, func(units.BytesPerSecond) {}
+	}
+
 	p := oututil.StartPrinting()
 	p.Println(format(units.BytesPerSecond(0)))
 
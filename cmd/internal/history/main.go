@@ -0,0 +1,202 @@
+// Package history implements the subcommand reporting on the results kept
+// by the daemon.
+package history
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"framey/assignment/cmd/internal/exitcode"
+	"framey/assignment/cmd/internal/provider"
+	history2 "framey/assignment/internal/history"
+	"framey/assignment/internal/report"
+	"framey/assignment/internal/units"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	flagSet      = flag.NewFlagSet("history", flag.ExitOnError)
+	historyPath  = flagSet.String("history", defaultHistoryPath(), "History file written by the daemon")
+	since        = flagSet.Duration("since", 30*24*time.Hour, "Report on the runs this recent")
+	from         = flagSet.String("from", "", "Report on the runs from this date or RFC 3339 time on, instead of -since")
+	to           = flagSet.String("to", "", "Report on the runs before this date or RFC 3339 time")
+	providerName = flagSet.String("provider", "", "Only report on this provider")
+	last         = flagSet.Int("last", 10, "Number of recent runs to list")
+	format       = flagSet.String("format", "text", "Output format: text or json")
+)
+
+// SchemaVersion is the version of the json output.
+const SchemaVersion = 1
+
+func defaultHistoryPath() string {
+	p, err := history2.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+func Main(args []string) {
+	if err := flagSet.Parse(args[1:]); err != nil {
+		panic(err)
+	}
+	if *last < 0 {
+		exitcode.Fatalf(exitcode.Usage, "-last must not be negative")
+	}
+	start, end, err := timeRange()
+	if err != nil {
+		exitcode.Fatalf(exitcode.Usage, "%v", err)
+	}
+	results, err := history2.Store{Path: *historyPath}.Load(start, end)
+	if err != nil {
+		log.Fatalf("Error loading history: %v", err)
+	}
+	if *providerName != "" {
+		name, err := provider.Canonical(*providerName)
+		if err != nil {
+			exitcode.Fatalf(exitcode.Usage, "%v", err)
+		}
+		results = onlyProvider(results, name)
+	}
+
+	recent := results
+	if len(recent) > *last {
+		recent = recent[len(recent)-*last:]
+	}
+	stats := history2.Compute(results, time.Local)
+
+	switch *format {
+	case "text":
+		err = printText(os.Stdout, start, end, recent, stats)
+	case report.JSON:
+		err = json.NewEncoder(os.Stdout).Encode(struct {
+			SchemaVersion int             `json:"schema_version"`
+			From          time.Time       `json:"from"`
+			To            *time.Time      `json:"to,omitempty"`
+			Recent        []report.Result `json:"recent"`
+			Stats         history2.Stats  `json:"stats"`
+		}{SchemaVersion, start, optionalTime(end), recent, stats})
+	default:
+		exitcode.Fatalf(exitcode.Usage, "Unknown output format: %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
+
+// Returns the range of times selected by the flags. The end is zero if open.
+func timeRange() (start, end time.Time, err error) {
+	start = time.Now().Add(-*since)
+	if *from != "" {
+		if start, err = parseTime(*from); err != nil {
+			return
+		}
+	}
+	if *to != "" {
+		end, err = parseTime(*to)
+	}
+	return
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, expected a date or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func onlyProvider(results []report.Result, name string) []report.Result {
+	var kept []report.Result
+	for _, r := range results {
+		if r.Provider == name {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func printText(w io.Writer, start, end time.Time, recent []report.Result, s history2.Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Recent runs\n")
+	for _, r := range recent {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t", r.Timestamp.Local().Format("2006-01-02 15:04"), r.Provider, server(r))
+		if r.Error != "" {
+			fmt.Fprintf(tw, "failed: %s\n", r.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "down %v\tup %v\t%.1f ms\n",
+			bps(r.Download.BitsPerSecond), bps(r.Upload.BitsPerSecond), r.Latency.Ms)
+	}
+
+	until := "now"
+	if !end.IsZero() {
+		until = end.Local().Format("2006-01-02 15:04")
+	}
+	fmt.Fprintf(tw, "\n%d runs from %s to %s, %d failed\n",
+		s.Runs, start.Local().Format("2006-01-02 15:04"), until, s.Failures)
+
+	fmt.Fprintf(tw, "\nBy provider and server\tRuns\tFailed\tDownload min / median / p95 / max\tUpload median\tLatency median\n")
+	groups := func(gs []history2.Group) {
+		for _, g := range gs {
+			name := g.Provider
+			if g.Server != "" {
+				name = "  " + g.Provider + " " + g.Server
+			}
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\t%v\t%.1f ms\n",
+				name, g.Runs, g.Failures, speeds(g.Download), bps(g.Upload.Median), g.Latency.Median)
+		}
+	}
+	groups(s.Providers)
+	groups(s.Servers)
+
+	fmt.Fprintf(tw, "\nBy time of day\tRuns\tDownload min / median / p95 / max\n")
+	for _, p := range s.TimeOfDay {
+		fmt.Fprintf(tw, "  %s %02d:00-%02d:00\t%d\t%s\n",
+			p.Provider, p.From, p.To, p.Download.Count, speeds(p.Download))
+	}
+
+	fmt.Fprintf(tw, "\nBy week\tRuns\tDownload median\tChange\n")
+	for _, wk := range s.Trend {
+		change := ""
+		if wk.Change != nil {
+			change = fmt.Sprintf("%+.1f%%", *wk.Change*100)
+		}
+		fmt.Fprintf(tw, "  %s week of %s\t%d\t%v\t%s\n",
+			wk.Provider, wk.Start.Format("2006-01-02"), wk.Download.Count, bps(wk.Download.Median), change)
+	}
+	return tw.Flush()
+}
+
+func server(r report.Result) string {
+	if r.Server != nil {
+		return fmt.Sprintf("%d %s", r.Server.ID, r.Server.Sponsor)
+	}
+	return r.ServerKey()
+}
+
+func speeds(s history2.Summary) string {
+	if s.Count == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%v / %v / %v / %v", bps(s.Min), bps(s.Median), bps(s.P95), bps(s.Max))
+}
+
+func bps(f float64) units.BitsPerSecond {
+	return units.BitsPerSecond(f)
+}
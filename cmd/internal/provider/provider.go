@@ -0,0 +1,37 @@
+// Package provider looks up the provider subcommands by name for the long
+// running subcommands, which run tests repeatedly.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/cmd/internal/fast"
+	"framey/assignment/cmd/internal/speedtest"
+	"framey/assignment/internal/report"
+)
+
+// Canonical returns the canonical name of a provider given by name or by the
+// alias of its subcommand.
+func Canonical(name string) (string, error) {
+	switch name {
+	case report.Speedtest, "st":
+		return report.Speedtest, nil
+	case report.Fast, "f":
+		return report.Fast, nil
+	default:
+		return "", fmt.Errorf("unknown provider: %q", name)
+	}
+}
+
+// Runner returns a function running a test against the named provider,
+// configured by the provider subcommand's flags in args.
+func Runner(name string, args []string) (func(ctx context.Context) *report.Result, error) {
+	name, err := Canonical(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == report.Speedtest {
+		return speedtest.Runner(args), nil
+	}
+	return fast.Runner(args), nil
+}
@@ -0,0 +1,17 @@
+// Package serve implements the subcommands serving test results to other
+// systems.
+package serve
+
+import (
+	"fmt"
+	"framey/assignment/cmd/internal/exitcode"
+	"os"
+)
+
+func Main(args []string) {
+	if len(args) < 2 || args[1] != "metrics" {
+		fmt.Fprintf(os.Stderr, "USAGE\n  %s serve metrics [OPTIONS] [-- PROVIDER OPTIONS]\n", os.Args[0])
+		os.Exit(exitcode.Usage)
+	}
+	serveMetrics(args[2:])
+}
@@ -0,0 +1,206 @@
+package serve
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"framey/assignment/cmd/internal/exitcode"
+	"framey/assignment/cmd/internal/provider"
+	"framey/assignment/internal/metrics"
+	"framey/assignment/internal/report"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	metricsFlags = flag.NewFlagSet("serve metrics", flag.ExitOnError)
+	listen       = metricsFlags.String("listen", ":9516", "Address to serve /metrics on")
+	providerName = metricsFlags.String("provider", report.Speedtest, "Provider to test: speedtest.net or fast.com")
+	interval     = metricsFlags.Duration("interval", 30*time.Minute, "Time between tests, or 0 to test when scraped")
+	minInterval  = metricsFlags.Duration("interval.min", 5*time.Minute, "Minimum time between tests when testing on scrape")
+	seriesTTL    = metricsFlags.Duration("series.ttl", 24*time.Hour, "Time after which the histograms and counters of a server no longer tested are dropped")
+)
+
+func init() {
+	metricsFlags.Usage = func() {
+		fmt.Fprintf(metricsFlags.Output(), "USAGE\n  serve metrics [OPTIONS] [-- PROVIDER OPTIONS]\n")
+		metricsFlags.PrintDefaults()
+	}
+}
+
+// Runs tests on a schedule, or when scraped, and exposes their results to
+// Prometheus.
+func serveMetrics(args []string) {
+	if err := metricsFlags.Parse(args); err != nil {
+		panic(err)
+	}
+	name, err := provider.Canonical(*providerName)
+	if err != nil {
+		exitcode.Fatalf(exitcode.Usage, "%v", err)
+	}
+	run, err := provider.Runner(name, metricsFlags.Args())
+	if err != nil {
+		exitcode.Fatalf(exitcode.Usage, "%v", err)
+	}
+
+	e := newExporter(name, *seriesTTL)
+	mux := http.NewServeMux()
+	if *interval > 0 {
+		go func() {
+			for {
+				e.record(run(context.Background()))
+				time.Sleep(*interval)
+			}
+		}()
+		mux.Handle("/metrics", &e.reg)
+	} else {
+		mux.Handle("/metrics", e.testOnScrape(run, *minInterval))
+	}
+
+	log.Printf("Serving metrics on %s/metrics", *listen)
+	log.Fatal(http.ListenAndServe(*listen, mux))
+}
+
+// Buckets for speeds in bits per second, from 1 Mb/s to 10 Gb/s, and for
+// latencies in seconds.
+var (
+	speedBuckets   = []float64{1e6, 5e6, 10e6, 25e6, 50e6, 100e6, 250e6, 500e6, 1e9, 2.5e9, 5e9, 10e9}
+	latencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+)
+
+type exporter struct {
+	reg metrics.Registry
+
+	download, upload, latency, jitter *metrics.Family
+	downloads, uploads, latencies     *metrics.Family
+	bytes, tests, failures            *metrics.Family
+	lastRun, lastSuccess              *metrics.Family
+
+	// When each server was last tested, to drop its series once it hasn't
+	// been for ttl.
+	ttl    time.Duration
+	tested map[string]time.Time
+
+	// Guards the fields below, for tests run on scrape.
+	mu      sync.Mutex
+	last    time.Time
+	running bool
+}
+
+// Returns an exporter for tests against the named provider.
+//
+// Measurements are labelled by server as well. The servers tested change from
+// one test to the next, fast.com's with every test, so the "last test" gauges
+// only keep the last server's series and the others are dropped once the
+// server hasn't been tested for ttl.
+func newExporter(provider string, ttl time.Duration) *exporter {
+	e := &exporter{ttl: ttl, tested: make(map[string]time.Time)}
+	r := &e.reg
+	e.download = r.NewGauge("netspeed_download_bits_per_second",
+		"Download speed of the last test.", "provider", "server")
+	e.upload = r.NewGauge("netspeed_upload_bits_per_second",
+		"Upload speed of the last test.", "provider", "server")
+	e.latency = r.NewGauge("netspeed_latency_seconds",
+		"Unloaded latency of the last test.", "provider", "server")
+	e.jitter = r.NewGauge("netspeed_jitter_seconds",
+		"Latency jitter of the last test, speedtest.net only.", "provider", "server")
+	e.downloads = r.NewHistogram("netspeed_download_speed_bits_per_second",
+		"Download speeds of all tests.", speedBuckets, "provider", "server")
+	e.uploads = r.NewHistogram("netspeed_upload_speed_bits_per_second",
+		"Upload speeds of all tests.", speedBuckets, "provider", "server")
+	e.latencies = r.NewHistogram("netspeed_unloaded_latency_seconds",
+		"Unloaded latencies of all tests.", latencyBuckets, "provider", "server")
+	e.bytes = r.NewCounter("netspeed_transferred_bytes_total",
+		"Bytes transferred by the speed probes.", "provider", "server", "direction")
+	e.tests = r.NewCounter("netspeed_tests_total",
+		"Tests run.", "provider")
+	e.failures = r.NewCounter("netspeed_test_failures_total",
+		"Tests that failed.", "provider")
+	e.lastRun = r.NewGauge("netspeed_last_test_timestamp_seconds",
+		"When the last test started, in seconds since the epoch.", "provider")
+	e.lastSuccess = r.NewGauge("netspeed_last_test_success",
+		"Whether the last test succeeded.", "provider")
+
+	// Counters exist from the start, so that rates work from the first
+	// failure.
+	e.tests.Add(0, provider)
+	e.failures.Add(0, provider)
+	return e
+}
+
+// Starts a test in the background when scraped, unless the last one is more
+// recent than min or still running. Either way the scrape is answered right
+// away with the results recorded so far, since a test takes longer than
+// Prometheus waits for a scrape.
+func (e *exporter) testOnScrape(run func(context.Context) *report.Result, min time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		if !e.running && (e.last.IsZero() || time.Since(e.last) >= min) {
+			e.last = time.Now()
+			e.running = true
+			go func() {
+				e.record(run(context.Background()))
+				e.mu.Lock()
+				e.running = false
+				e.mu.Unlock()
+			}()
+		}
+		e.mu.Unlock()
+		e.reg.ServeHTTP(w, r)
+	})
+}
+
+// Records whatever a test got to measure, even if it failed midway. Tests are
+// recorded one at a time.
+func (e *exporter) record(res *report.Result) {
+	p, s := res.Provider, res.ServerKey()
+
+	e.tests.Add(1, p)
+	e.lastRun.Set(float64(res.Timestamp.UnixNano())/1e9, p)
+	if res.Error != "" {
+		log.Printf("Test failed: %s", res.Error)
+		e.failures.Add(1, p)
+		e.lastSuccess.Set(0, p)
+	} else {
+		e.lastSuccess.Set(1, p)
+	}
+
+	for _, f := range []*metrics.Family{e.download, e.upload, e.latency, e.jitter} {
+		f.Reset()
+	}
+	if s != "" {
+		e.tested[s] = res.Timestamp
+	}
+	e.expire(res.Timestamp)
+
+	if l := res.Latency; l != nil {
+		e.latency.Set(l.Ms/1000, p, s)
+		e.jitter.Set(l.JitterMs/1000, p, s)
+		e.latencies.Observe(l.Ms/1000, p, s)
+	}
+	if t := res.Download; t != nil {
+		e.download.Set(t.BitsPerSecond, p, s)
+		e.downloads.Observe(t.BitsPerSecond, p, s)
+		e.bytes.Add(float64(t.Bytes), p, s, "download")
+	}
+	if t := res.Upload; t != nil {
+		e.upload.Set(t.BitsPerSecond, p, s)
+		e.uploads.Observe(t.BitsPerSecond, p, s)
+		e.bytes.Add(float64(t.Bytes), p, s, "upload")
+	}
+}
+
+// Drops the series of the servers last tested more than ttl before now.
+func (e *exporter) expire(now time.Time) {
+	for s, at := range e.tested {
+		if now.Sub(at) <= e.ttl {
+			continue
+		}
+		delete(e.tested, s)
+		for _, f := range []*metrics.Family{e.downloads, e.uploads, e.latencies, e.bytes} {
+			f.Delete("server", s)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"framey/assignment/internal/report"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testResult(server uint64, at time.Time) *report.Result {
+	res := report.New(report.Speedtest)
+	res.Timestamp = at
+	res.Server = &report.Server{ID: server}
+	res.Latency = &report.Latency{Ms: 10}
+	res.Download = &report.Transfer{BitsPerSecond: 100e6, Bytes: 1000}
+	return res
+}
+
+func scrape(t *testing.T, e *exporter) string {
+	var buf bytes.Buffer
+	if err := e.reg.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestExporter_Record(t *testing.T) {
+	e := newExporter(report.Speedtest, time.Hour)
+	start := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	e.record(testResult(1, start))
+	e.record(testResult(2, start.Add(time.Minute)))
+
+	text := scrape(t, e)
+	for _, want := range []string{
+		`netspeed_download_bits_per_second{provider="speedtest.net",server="2"} 1e+08`,
+		`netspeed_download_speed_bits_per_second_count{provider="speedtest.net",server="1"} 1`,
+		`netspeed_download_speed_bits_per_second_count{provider="speedtest.net",server="2"} 1`,
+		`netspeed_transferred_bytes_total{provider="speedtest.net",server="1",direction="download"} 1000`,
+		`netspeed_tests_total{provider="speedtest.net"} 2`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Missing %s in:\n%s", want, text)
+		}
+	}
+	// The last test was against another server.
+	if strings.Contains(text, `netspeed_download_bits_per_second{provider="speedtest.net",server="1"}`) {
+		t.Errorf("Stale gauge in:\n%s", text)
+	}
+
+	// Server 1 hasn't been tested for longer than the TTL.
+	e.record(testResult(2, start.Add(2*time.Hour)))
+	text = scrape(t, e)
+	if strings.Contains(text, `server="1"`) {
+		t.Errorf("Expired series in:\n%s", text)
+	}
+	if !strings.Contains(text, `netspeed_download_speed_bits_per_second_count{provider="speedtest.net",server="2"} 2`) {
+		t.Errorf("Missing server 2 in:\n%s", text)
+	}
+}
+
+func TestExporter_RecordFailure(t *testing.T) {
+	e := newExporter(report.Fast, time.Hour)
+	res := report.New(report.Fast)
+	res.Fail(context.DeadlineExceeded)
+	e.record(res)
+
+	text := scrape(t, e)
+	for _, want := range []string{
+		`netspeed_test_failures_total{provider="fast.com"} 1`,
+		`netspeed_last_test_success{provider="fast.com"} 0`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Missing %s in:\n%s", want, text)
+		}
+	}
+}
+
+func TestExporter_TestOnScrape(t *testing.T) {
+	e := newExporter(report.Speedtest, time.Hour)
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	release := make(chan struct{})
+	run := func(context.Context) *report.Result {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return testResult(1, time.Now())
+	}
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+	waitIdle := func() {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			e.mu.Lock()
+			running := e.running
+			e.mu.Unlock()
+			if !running {
+				return
+			}
+		}
+		t.Fatalf("The test is still running")
+	}
+
+	h := e.testOnScrape(run, time.Hour)
+	serve := func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != 200 {
+			t.Fatalf("Scrape returned %d", w.Code)
+		}
+	}
+
+	// The scrape doesn't wait for the test, nor does the one after it start
+	// another.
+	serve()
+	serve()
+	close(release)
+	waitIdle()
+	if n := callCount(); n != 1 {
+		t.Errorf("Ran %d tests, expected 1", n)
+	}
+
+	// The last test is more recent than the minimum interval.
+	serve()
+	waitIdle()
+	if n := callCount(); n != 1 {
+		t.Errorf("Ran %d tests, expected 1", n)
+	}
+
+	h = e.testOnScrape(run, 0)
+	serve()
+	waitIdle()
+	if n := callCount(); n != 2 {
+		t.Errorf("Ran %d tests, expected 2", n)
+	}
+}
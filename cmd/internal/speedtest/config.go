@@ -0,0 +1,33 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	speedtest2 "framey/assignment/pkg/speedtest"
+)
+
+// Loads the client configuration from the file or flags given, falling back
+// to speedtest.net.
+//
+func loadConfig(ctx context.Context, client *speedtest2.Client) (speedtest2.Config, error) {
+	switch {
+	case *cfgFile != "":
+		cfg, err := speedtest2.LoadConfigFile(*cfgFile)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to load configuration file: %v", err)
+		}
+		return cfg, nil
+	case *clientIP != "":
+		// The location is filled in by locateClient.
+		if !location.set && *city == "" {
+			return speedtest2.Config{}, fmt.Errorf("-client.ip requires -location or -location.city")
+		}
+		return speedtest2.Config{IP: *clientIP, ISP: "unknown ISP"}, nil
+	default:
+		cfg, err := client.CachedConfig(ctx, discoveryCache())
+		if err != nil {
+			return cfg, fmt.Errorf("failed to load speedtest.net configuration: %v", err)
+		}
+		return cfg, nil
+	}
+}
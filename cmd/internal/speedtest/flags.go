@@ -2,6 +2,10 @@ package speedtest
 
 import (
 	"flag"
+	"framey/assignment/cmd/internal/cmdutil"
+	"framey/assignment/internal/diskcache"
+	"framey/assignment/internal/geo"
+	"framey/assignment/pkg/retry"
 	"framey/assignment/pkg/speedtest"
 	"strconv"
 	"strings"
@@ -9,20 +13,87 @@ import (
 )
 
 var (
-	flagSet  = flag.NewFlagSet("speedtest", flag.ExitOnError)
-	fmtBytes = flagSet.Bool("bytes", false, "Display speeds in SI bytes (default is bits)")
-	list     = flagSet.Bool("list", false, "List the available servers and exit")
-	srvID    = flagSet.Uint64("server", 0, "Override automatic server selection")
-	cfgTime  = flagSet.Duration("time.config", 1*time.Second, "Timeout for getting initial configuration")
-	pngTime  = flagSet.Duration("time.latency", 1*time.Second, "Timeout for latency detection phase")
-	dlTime   = flagSet.Duration("time.download", 10*time.Second, "Maximum time to spend in download probe phase")
-	ulTime   = flagSet.Duration("time.upload", 10*time.Second, "Maximum time to spend in upload probe phase")
+	flagSet    = flag.NewFlagSet("speedtest", flag.ExitOnError)
+	results    = cmdutil.Register(flagSet)
+	fmtBytes   = flagSet.Bool("bytes", false, "Display speeds in SI bytes (default is bits)")
+	verbose    = flagSet.Bool("v", false, "Print a per-phase breakdown of request timings, which machine readable results always include")
+	list       = flagSet.Bool("list", false, "List the available servers as text and exit")
+	srvID      = flagSet.Uint64("server", 0, "Override automatic server selection")
+	selectBy   = flagSet.String("select", "latency", "Server selection strategy: closest, latency, jitter, throughput or random")
+	candidates = flagSet.Int("select.candidates", speedtest.DefaultCandidates, "Number of closest servers the selection strategy considers")
+	source     = flagSet.String("source", "", "Local IP address or network interface to test from")
+	proxy      = flagSet.String("proxy", "", "Proxy URL (http://, https:// or socks5://, optionally with user:password@)")
+	retries    = flagSet.Int("retries", retry.DefaultPolicy.Attempts-1, "Number of times to retry failed discovery requests")
+	cfgTime    = flagSet.Duration("time.config", 1*time.Second, "Timeout for getting initial configuration")
+	pngTime    = flagSet.Duration("time.latency", 1*time.Second, "Timeout for latency detection phase")
+	dlTime     = flagSet.Duration("time.download", 10*time.Second, "Maximum time to spend in download probe phase")
+	ulTime     = flagSet.Duration("time.upload", 10*time.Second, "Maximum time to spend in upload probe phase")
+
+	cfgFile  = flagSet.String("config.file", "", "Read the client configuration from a saved speedtest-config.php document")
+	srvFile  = flagSet.String("servers.file", "", "Read the server list from a saved speedtest-servers.php document")
+	clientIP = flagSet.String("client.ip", "", "Skip loading the configuration and use this client IP, with -location or -location.city")
+
+	cacheDir     = flagSet.String("cache.dir", defaultCacheDir(), "Directory to cache the configuration and server list in")
+	cacheBypass  = flagSet.Bool("cache.bypass", false, "Neither read nor write the cache")
+	cacheRefresh = flagSet.Bool("cache.refresh", false, "Download the configuration and server list even if they are cached")
+	cfgTTL       = flagSet.Duration("cache.ttl.config", 15*time.Minute, "How long a cached configuration is fresh")
+	srvTTL       = flagSet.Duration("cache.ttl.servers", 24*time.Hour, "How long a cached server list is fresh")
 )
 
-var srvBlk serverIDList
+var (
+	srvBlk    serverIDList
+	srvAllow  serverIDList
+	srvFilter filterValue
+	location  coordinatesValue
+	city      = flagSet.String("location.city", "", "Locate the client in the named city, e.g. \"Paris, FR\", instead of by IP")
+)
 
 func init() {
 	flagSet.Var(&srvBlk, "server_blocklist", "CSV of server IDs to ignore")
+	flagSet.Var(&srvAllow, "server_allowlist", "CSV of server IDs to choose from")
+	flagSet.Var(&location, "location", "Client coordinates as latitude,longitude, instead of locating by IP")
+	flagSet.Var(&srvFilter, "filter", "Server filter expression, e.g. \"cc=DE,NL; sponsor~(?i)telekom; radius=800\"")
+}
+
+// Returns the server filter combining all the filtering flags.
+func serverFilter() speedtest.Filter {
+	f := srvFilter.f
+	f.Block = append(append([]speedtest.ServerID(nil), f.Block...), srvBlk...)
+	f.Allow = append(append([]speedtest.ServerID(nil), f.Allow...), srvAllow...)
+	return f
+}
+
+type coordinatesValue struct {
+	c   geo.Coordinates
+	set bool
+}
+
+func (v *coordinatesValue) Set(s string) (err error) {
+	v.c, err = geo.ParseCoordinates(s)
+	v.set = err == nil
+	return
+}
+
+func (v *coordinatesValue) String() string {
+	if !v.set {
+		return ""
+	}
+	return v.c.String()
+}
+
+type filterValue struct {
+	expr string
+	f    speedtest.Filter
+}
+
+func (v *filterValue) Set(s string) (err error) {
+	v.f, err = speedtest.ParseFilter(s)
+	v.expr = s
+	return
+}
+
+func (v *filterValue) String() string {
+	return v.expr
 }
 
 type serverIDList []speedtest.ServerID
@@ -42,9 +113,38 @@ func (l *serverIDList) Set(s string) (err error) {
 }
 
 func (l *serverIDList) String() string {
-	sl := make([]string, len(*l))[:0]
+	sl := make([]string, len(*l))
 	for i, j := range *l {
 		sl[i] = strconv.Itoa(int(j))
 	}
 	return strings.Join(sl, ",")
 }
+
+// Returns the retry policy for discovery requests selected by the flags.
+func retryPolicy() retry.Policy {
+	p := retry.DefaultPolicy
+	p.Attempts = *retries + 1
+	return p
+}
+
+func defaultCacheDir() string {
+	d, err := diskcache.DefaultDir()
+	if err != nil {
+		return ""
+	}
+	return string(d)
+}
+
+// Returns the discovery cache selected by the flags, or nil to bypass it.
+func discoveryCache() *speedtest.Cache {
+	if *cacheBypass || *cacheDir == "" {
+		return nil
+	}
+	return &speedtest.Cache{
+		Dir:        *cacheDir,
+		Scope:      *source + " " + *proxy,
+		ConfigTTL:  *cfgTTL,
+		ServersTTL: *srvTTL,
+		Refresh:    *cacheRefresh,
+	}
+}
@@ -3,52 +3,122 @@ package speedtest
 import (
 	"context"
 	"fmt"
+	"framey/assignment/internal/geo"
+	"framey/assignment/pkg/retry"
 	speedtest2 "framey/assignment/pkg/speedtest"
-	"log"
 )
 
-// Loads the list of servers and exits the program on failure.
+// Loads the list of servers.
 //
-func listServers(
+func loadServers(
 	ctx context.Context,
 	client *speedtest2.Client,
-) []speedtest2.Server {
-	servers, err := client.LoadAllServers(ctx)
+) ([]speedtest2.Server, error) {
+	var (
+		servers []speedtest2.Server
+		err     error
+	)
+	if *srvFile != "" {
+		servers, err = speedtest2.LoadServersFile(*srvFile)
+	} else {
+		servers, err = client.CachedServers(ctx, discoveryCache())
+	}
 	if err != nil {
-		log.Fatalf("Failed to load server list: %v\n", err)
+		return nil, fmt.Errorf("failed to load server list: %v", err)
 	}
 	if len(servers) == 0 {
-		log.Fatalf("No servers found somehow...")
+		return nil, fmt.Errorf("no servers found somehow...")
 	}
-	if len(srvBlk) != 0 {
-		servers = pruneBlockedServers(servers)
+	return servers, nil
+}
+
+// Narrows the servers down to those passing the filter flags for a client at
+// origin and fails if there are none left.
+//
+func filterServers(all *speedtest2.ServerIndex, origin geo.Coordinates) (*speedtest2.ServerIndex, error) {
+	servers := serverFilter().Narrow(all, origin)
+	if servers.Len() == 0 {
+		return nil, fmt.Errorf("no servers match the filters")
 	}
-	return servers
+	return servers, nil
 }
 
-func pruneBlockedServers(servers []speedtest2.Server) []speedtest2.Server {
-	n := make([]speedtest2.Server, len(servers)-len(srvBlk))[:0]
-	for _, s := range servers {
-		var i bool
-		for _, b := range srvBlk {
-			if s.ID == b {
-				i = true
-			}
+// The index over the last server list loaded, kept for as long as the list
+// doesn't change, since long running subcommands test over and over.
+var lastIndex *speedtest2.ServerIndex
+
+// Returns an index over servers, reusing the last one if it indexes the same
+// list.
+//
+func indexServers(servers []speedtest2.Server) *speedtest2.ServerIndex {
+	if lastIndex == nil || !sameServers(lastIndex.Servers(), servers) {
+		lastIndex = speedtest2.NewServerIndex(servers)
+	}
+	return lastIndex
+}
+
+func sameServers(a, b []speedtest2.Server) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
-		if !i {
-			n = append(n, s)
+	}
+	return true
+}
+
+// Overrides the client location if the flags ask for it.
+//
+func locateClient(cfg *speedtest2.Config, servers []speedtest2.Server) error {
+	switch {
+	case location.set:
+		cfg.Locate(location.c, speedtest2.LocationManual)
+	case *city != "":
+		c, err := speedtest2.LocateCity(servers, *city)
+		if err != nil {
+			return fmt.Errorf("failed to locate client: %v", err)
 		}
+		cfg.Locate(c, speedtest2.LocationCity)
 	}
-	return n
+	return nil
 }
 
 // Iterates through the list of server and prints them out.
 //
-func printServers(client *speedtest2.Client) {
+func printServers() error {
+	client, _, err := newClient()
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), *cfgTime)
 	defer cancel()
+	ctx = retry.WithPolicy(ctx, retryPolicy())
+
+	servers, err := loadServers(ctx, client)
+	if err != nil {
+		return err
+	}
 
-	for _, s := range listServers(ctx, client) {
+	// Only bother locating the client if the filters need it.
+	var cfg speedtest2.Config
+	if serverFilter().NeedsOrigin() {
+		if cfg, err = loadConfig(ctx, client); err != nil {
+			return err
+		}
+		if err := locateClient(&cfg, servers); err != nil {
+			return err
+		}
+	}
+
+	servers = serverFilter().ApplyIndex(indexServers(servers), cfg.Coordinates)
+	if len(servers) == 0 {
+		return fmt.Errorf("no servers match the filters")
+	}
+	for _, s := range servers {
 		fmt.Println(s)
 	}
+	return nil
 }
@@ -3,35 +3,150 @@ package speedtest
 import (
 	"context"
 	"fmt"
+	"framey/assignment/cmd/internal/cmdutil"
+	"framey/assignment/cmd/internal/exitcode"
+	"framey/assignment/internal/geo"
+	"framey/assignment/internal/report"
+	"framey/assignment/pkg/retry"
 	"framey/assignment/pkg/speedtest"
+	"framey/assignment/pkg/transport"
+	"io"
+	"io/ioutil"
 	"log"
+	"os"
+	"strconv"
 )
 
+// Where human readable progress goes, nowhere unless the output format is
+// text.
+var out io.Writer = os.Stdout
+
 func Main(args []string) {
 	err := flagSet.Parse(args[1:])
 	if err != nil {
 		panic(err)
 	}
-
-	var client speedtest.Client
+	if !results.Check() {
+		out = ioutil.Discard
+	}
 
 	if *list {
-		printServers(&client)
+		if !textOutput() {
+			exitcode.Fatalf(exitcode.Usage, "-list only prints text, it can't be combined with -format=%s", results.Format)
+		}
+		if err := printServers(); err != nil {
+			log.Fatal(err)
+		}
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), *cfgTime)
+	res := report.New(report.Speedtest)
+	results.Finish(res, run(context.Background(), res), retryPolicy())
+}
+
+func textOutput() bool {
+	return out != ioutil.Discard
+}
+
+// Runner parses the flags in args and returns a function running a test with
+// them, for the long running subcommands. Nothing is printed.
+func Runner(args []string) func(ctx context.Context) *report.Result {
+	if err := flagSet.Parse(args); err != nil {
+		panic(err)
+	}
+	out = ioutil.Discard
+	return results.Runner(report.Speedtest, retryPolicy(), run)
+}
+
+// Runs the test, filling res in as it goes, until done or ctx is canceled.
+func run(ctx context.Context, res *report.Result) error {
+	client, proxy, err := newClient()
+	if err != nil {
+		return err
+	}
+	res.Proxy = proxy
+
+	cfgCtx, cancel := context.WithTimeout(ctx, *cfgTime)
 	defer cancel()
+	cfgCtx = retry.WithPolicy(cfgCtx, retryPolicy())
 
-	cfg, err := client.Config(ctx)
+	cfg, err := loadConfig(cfgCtx, client)
 	if err != nil {
-		log.Fatalf("Error loading speedtest.net configuration: %v", err)
+		return err
 	}
-	fmt.Printf("Testing from %s (%s)...\n", cfg.ISP, cfg.IP)
-	servers := listServers(ctx, &client)
+	fmt.Fprintf(out, "Testing from %s (%s)...\n", cfg.ISP, cfg.IP)
+	all, err := loadServers(cfgCtx, client)
+	if err != nil {
+		return err
+	}
+	if err := locateClient(&cfg, all); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Client located at %v (%s)\n", cfg.Coordinates, cfg.LocationSource)
+	res.Client = clientReport(cfg)
 
-	server := selectServer(&client, cfg, servers)
+	servers, err := filterServers(indexServers(all), cfg.Coordinates)
+	if err != nil {
+		return err
+	}
+	sel, err := selectServer(ctx, client, cfg, servers)
+	if err != nil {
+		return err
+	}
+	res.Server = serverReport(sel)
+	res.Latency = &report.Latency{Ms: ms(sel.Latency), JitterMs: ms(sel.Jitter)}
+
+	if res.Download, err = download(ctx, client, sel.Server); err != nil {
+		return err
+	}
+	res.Upload, err = upload(ctx, client, sel.Server)
+	return err
+}
+
+// Returns a client configured by the flags, after telling about any proxy,
+// along with the proxy with any password redacted.
+func newClient() (*speedtest.Client, string, error) {
+	opts := transport.Options{Source: *source, Proxy: *proxy}
+	client, err := speedtest.NewClient(opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to configure client: %v", err)
+	}
+
+	p, err := cmdutil.Proxy(out, opts, "https://www.speedtest.net/")
+	if err != nil {
+		return nil, "", err
+	}
+	return client, p, nil
+}
 
-	download(&client, server)
-	upload(&client, server)
+func clientReport(cfg speedtest.Config) report.Client {
+	lat, lon := degrees(cfg.Coordinates.Latitude), degrees(cfg.Coordinates.Longitude)
+	return report.Client{
+		IP:             cfg.IP,
+		ISP:            cfg.ISP,
+		Latitude:       &lat,
+		Longitude:      &lon,
+		LocationSource: string(cfg.LocationSource),
+	}
+}
+
+// Widens d without making up digits, so that 52.37 doesn't become
+// 52.369998931884766.
+func degrees(d geo.Degrees) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(d), 'g', -1, 32), 64)
+	return f
+}
+
+func serverReport(sel speedtest.Selection) *report.Server {
+	s := sel.Server
+	return &report.Server{
+		ID:         uint64(s.ID),
+		Name:       s.Name,
+		Sponsor:    s.Sponsor,
+		Country:    s.Country,
+		CC:         s.CC,
+		Host:       s.Host,
+		URL:        s.URL,
+		DistanceKm: float64(sel.Distance),
+	}
 }
@@ -3,74 +3,111 @@ package speedtest
 import (
 	"context"
 	"fmt"
-	"framey/assignment/internal/geo"
+	"framey/assignment/internal/diskcache"
+	"framey/assignment/internal/units"
 	speedtest2 "framey/assignment/pkg/speedtest"
 	"log"
 	"time"
 )
 
-// Selects a server to use, either selected by the user or by a low latency
-// selection algorithm.
+// Selects a server to use, either selected by the user or by the selection
+// strategy picked with the flags.
 //
-func selectServer(client *speedtest2.Client, cfg speedtest2.Config, servers []speedtest2.Server) speedtest2.Server {
-	var (
-		distance geo.Kilometers
-		latency  time.Duration
-		server   speedtest2.Server
-	)
-
-	ctx, cancel := context.WithTimeout(context.Background(), *pngTime)
+func selectServer(
+	ctx context.Context,
+	client *speedtest2.Client,
+	cfg speedtest2.Config,
+	servers *speedtest2.ServerIndex,
+) (speedtest2.Selection, error) {
+	ctx, cancel := context.WithTimeout(ctx, *pngTime)
 	defer cancel()
 
+	var (
+		sel speedtest2.Selection
+		err error
+	)
 	if *srvID != 0 {
 		id := speedtest2.ServerID(*srvID)
+		one := servers.Subset(func(s speedtest2.Server) bool { return s.ID == id })
+		if one.Len() == 0 {
+			return sel, fmt.Errorf("server not found: %d", id)
+		}
 
-		// Meh, linear search.
-		i := -1
-		for j, s := range servers {
-			if s.ID == id {
-				i = j
-				break
-			}
+		// The closest of one server is that very server.
+		sel, err = speedtest2.Closest{}.Select(ctx, client, cfg.Coordinates, one)
+		if err != nil {
+			return sel, fmt.Errorf("failed to get latency for (%v): %v", one.Servers()[0], err)
 		}
-		if i == -1 {
-			log.Fatalf("Server not found: %d\n", id)
+	} else {
+		s, err := selector()
+		if err != nil {
+			return sel, err
 		}
-
-		server = servers[i]
-		l, err := server.AverageLatency(ctx, client, speedtest2.DefaultLatencySamples)
+		sel, err = s.Select(ctx, client, cfg.Coordinates, servers)
 		if err != nil {
-			log.Fatalf("Error getting latency for (%v): %v", server, err)
+			return sel, fmt.Errorf("failed to select a server: %v", err)
 		}
+	}
 
-		latency = l
-		distance = cfg.Coordinates.DistanceTo(server.Coordinates)
-	} else {
-		distanceMap := speedtest2.SortServersByDistance(servers, cfg.Coordinates)
+	fmt.Fprintf(out, "Using server %d hosted by %s (%s) [%v]: %.1f ms, jitter %.1f ms\n",
+		sel.Server.ID, sel.Server.Sponsor, sel.Server.Name, sel.Distance, ms(sel.Latency), ms(sel.Jitter))
 
-		// Truncate to just a few of the closest servers for the latency test.
-		const maxCloseServers = 5
-		closestServers := func() []speedtest2.Server {
-			if len(servers) > maxCloseServers {
-				return servers[:maxCloseServers]
-			} else {
-				return servers
-			}
-		}()
+	return sel, nil
+}
 
-		latencyMap, err := speedtest2.StableSortServersByAverageLatency(
-			closestServers, ctx, client, speedtest2.DefaultLatencySamples)
-		if err != nil {
-			log.Fatalf("Error getting server latencies: %v", err)
-		}
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
 
-		server = closestServers[0]
-		latency = latencyMap[server.ID]
-		distance = distanceMap[server.ID]
+// Returns the selection strategy named by the flags.
+func selector() (speedtest2.Selector, error) {
+	switch *selectBy {
+	case "closest":
+		return speedtest2.Closest{}, nil
+	case "latency":
+		return speedtest2.LowestLatency{Candidates: *candidates}, nil
+	case "jitter":
+		return speedtest2.LowestJitter{Candidates: *candidates}, nil
+	case "throughput":
+		return speedtest2.BestThroughput{
+			History:  loadThroughputHistory(),
+			Fallback: speedtest2.LowestLatency{Candidates: *candidates},
+		}, nil
+	case "random":
+		return speedtest2.RandomTopK{K: *candidates}, nil
+	default:
+		return nil, fmt.Errorf("unknown server selection strategy: %q", *selectBy)
 	}
+}
+
+const throughputEntry = "throughput"
+
+// The last download speed achieved against each server, kept in the cache
+// directory for the "throughput" selection strategy.
+type throughputHistory map[speedtest2.ServerID]units.BytesPerSecond
 
-	fmt.Printf("Using server %d hosted by %s (%s) [%v]: %.1f ms\n",
-		server.ID, server.Sponsor, server.Name, distance, float64(latency)/float64(time.Millisecond))
+func (h throughputHistory) Throughput(id speedtest2.ServerID) (units.BytesPerSecond, bool) {
+	b, ok := h[id]
+	return b, ok
+}
+
+func loadThroughputHistory() throughputHistory {
+	h := make(throughputHistory)
+	if c := discoveryCache(); c != nil {
+		// Without history, the fallback strategy kicks in.
+		_, _ = diskcache.Dir(c.Dir).Load(throughputEntry, &h)
+	}
+	return h
+}
 
-	return server
+func recordThroughput(id speedtest2.ServerID, speed units.BytesPerSecond) {
+	c := discoveryCache()
+	if c == nil {
+		return
+	}
+	h := loadThroughputHistory()
+	h[id] = speed
+	if err := diskcache.Dir(c.Dir).Store(throughputEntry, h); err != nil {
+		log.Printf("Could not record throughput history: %v", err)
+	}
 }
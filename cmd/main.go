@@ -3,7 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"framey/assignment/cmd/internal/daemon"
+	"framey/assignment/cmd/internal/exitcode"
 	"framey/assignment/cmd/internal/fast"
+	"framey/assignment/cmd/internal/history"
+	"framey/assignment/cmd/internal/serve"
 	"framey/assignment/cmd/internal/speedtest"
 	"os"
 	"strings"
@@ -23,6 +27,18 @@ var subcmds = []subcmd{
 		mainFunc: fast.Main,
 		aliases:  []string{"f", "fast.com"},
 	},
+	subcmd{
+		mainFunc: daemon.Main,
+		aliases:  []string{"daemon"},
+	},
+	subcmd{
+		mainFunc: history.Main,
+		aliases:  []string{"history"},
+	},
+	subcmd{
+		mainFunc: serve.Main,
+		aliases:  []string{"serve"},
+	},
 }
 
 func main() {
@@ -32,7 +48,7 @@ func main() {
 	s := getSubcmd()
 	if s == nil {
 		flag.Usage()
-		os.Exit(2)
+		os.Exit(exitcode.Usage)
 	}
 	s.mainFunc(flag.Args())
 }
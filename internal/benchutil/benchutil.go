@@ -0,0 +1,145 @@
+// Package benchutil reports the cost of the probes in benchmarks run against
+// local stand-ins for the providers.
+package benchutil
+
+import (
+	"framey/assignment/internal/units"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const gigabyte = 1000 * 1000 * 1000
+
+// Stream returns a stream for the probes to report their progress to, which
+// is drained like the CLI does.
+func Stream() chan units.BytesPerSecond {
+	stream := make(chan units.BytesPerSecond)
+	go func() {
+		for range stream {
+		}
+	}()
+	return stream
+}
+
+// Counter counts the bytes a stand-in server sends and receives.
+type Counter struct {
+	n int64
+}
+
+func (c *Counter) Add(n int) {
+	atomic.AddInt64(&c.n, int64(n))
+}
+
+// Reset returns the count and starts over from zero.
+func (c *Counter) Reset() int64 {
+	return atomic.SwapInt64(&c.n, 0)
+}
+
+// Payload is a buffer to serve downloads from without allocating.
+var Payload = make([]byte, 1<<20)
+
+// Send writes n bytes of payload to w and counts them.
+func (c *Counter) Send(w http.ResponseWriter, n int) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	for n > 0 {
+		p := Payload
+		if n < len(p) {
+			p = p[:n]
+		}
+		written, err := w.Write(p)
+		c.Add(written)
+		if err != nil {
+			return
+		}
+		n -= written
+	}
+}
+
+// Receive reads and counts the request body.
+func (c *Counter) Receive(r *http.Request) {
+	var buf [32 << 10]byte
+	for {
+		n, err := r.Body.Read(buf[:])
+		c.Add(n)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Run runs probe b.N times, with c counting the bytes it transfers, and
+// reports per GB transferred the CPU time, allocations and bytes allocated by
+// the process, as well as the peak number of goroutines. The stand-ins run in
+// the same process so their cost is included, which is why they must stay
+// trivial.
+func Run(b *testing.B, c *Counter, probe func() error) {
+	b.Helper()
+	b.ReportAllocs()
+	c.Reset()
+
+	peak := watchGoroutines()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	cpu, cpuOK := cpuTime()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := probe(); err != nil {
+			b.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	cpuAfter, _ := cpuTime()
+	runtime.ReadMemStats(&after)
+	goroutines := peak()
+
+	bytes := c.Reset()
+	if bytes == 0 {
+		b.Fatalf("Nothing was transferred")
+	}
+	perGB := float64(gigabyte) / float64(bytes)
+	b.ReportMetric(float64(bytes)/float64(b.N)/(1000*1000), "MB/op")
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)*perGB, "allocs/GB")
+	b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)*perGB, "B-alloc/GB")
+	if cpuOK {
+		b.ReportMetric(float64(cpuAfter-cpu)/float64(time.Millisecond)*perGB, "cpu-ms/GB")
+	}
+	b.ReportMetric(float64(goroutines), "peak-goroutines")
+}
+
+// Samples the number of goroutines until the returned function is called,
+// which returns the highest count seen.
+func watchGoroutines() func() int {
+	var (
+		peak = runtime.NumGoroutine()
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if n := runtime.NumGoroutine(); n > peak {
+					peak = n
+				}
+			}
+		}
+	}()
+	return func() int {
+		close(done)
+		wg.Wait()
+		return peak
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows || plan9 || js || wasip1
+// +build windows plan9 js wasip1
+
+package benchutil
+
+import "time"
+
+// CPU time isn't reported where getrusage is unavailable.
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Tuesday.
+	from := time.Date(2022, 3, 1, 10, 7, 30, 0, time.UTC)
+
+	for _, c := range []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2022, 3, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, 3, 1, 10, 15, 0, 0, time.UTC)},
+		{"5/15 * * * *", time.Date(2022, 3, 1, 10, 20, 0, 0, time.UTC)},
+		{"0 8-18/2 * * *", time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2022, 3, 2, 2, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2022, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2022, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC)},
+		// Either the 15th or a Friday.
+		{"0 0 15 * 5", time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(c.expected) {
+			t.Errorf("%q: got %v, expected %v", c.expr, got, c.expected)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"0 0 * JAN *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
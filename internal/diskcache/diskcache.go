@@ -0,0 +1,90 @@
+// Package diskcache stores small JSON documents on disk along with the time
+// they were stored.
+package diskcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Dir is a directory holding cache entries. It is created on first write.
+type Dir string
+
+// DefaultDir returns the per-user cache directory for this program.
+func DefaultDir() (Dir, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return Dir(filepath.Join(d, "framey-speedtest")), nil
+}
+
+type entry struct {
+	Stored time.Time       `json:"stored"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// Load decodes the named entry into v and returns the time it was stored. The
+// error satisfies os.IsNotExist if there is no such entry.
+func (d Dir) Load(name string, v interface{}) (time.Time, error) {
+	b, err := ioutil.ReadFile(d.path(name))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return time.Time{}, fmt.Errorf("diskcache: corrupt entry %q: %w", name, err)
+	}
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return time.Time{}, fmt.Errorf("diskcache: corrupt entry %q: %w", name, err)
+	}
+	return e.Stored, nil
+}
+
+// Store replaces the named entry with v. Readers never observe a partially
+// written entry.
+func (d Dir) Store(name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("diskcache: could not encode entry %q: %w", name, err)
+	}
+	b, err = json.Marshal(entry{Stored: time.Now(), Value: b})
+	if err != nil {
+		return fmt.Errorf("diskcache: could not encode entry %q: %w", name, err)
+	}
+
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(string(d), name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), d.path(name))
+}
+
+// Remove deletes the named entry, if it exists.
+func (d Dir) Remove(name string) error {
+	err := os.Remove(d.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (d Dir) path(name string) string {
+	return filepath.Join(string(d), name+".json")
+}
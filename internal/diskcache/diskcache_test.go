@@ -0,0 +1,41 @@
+package diskcache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDir_StoreLoad(t *testing.T) {
+	d := Dir(t.TempDir())
+
+	type doc struct{ A, B string }
+	in := doc{"a", "b"}
+	before := time.Now()
+	if err := d.Store("doc", in); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out doc
+	stored, err := d.Load("doc", &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out != in {
+		t.Errorf("got: %+v", out)
+	}
+	if stored.Before(before) || stored.After(time.Now()) {
+		t.Errorf("Unexpected store time %v", stored)
+	}
+}
+
+func TestDir_LoadMissing(t *testing.T) {
+	d := Dir(t.TempDir())
+	var v string
+	if _, err := d.Load("missing", &v); !os.IsNotExist(err) {
+		t.Errorf("got: %v", err)
+	}
+	if err := d.Remove("missing"); err != nil {
+		t.Errorf("got: %v", err)
+	}
+}
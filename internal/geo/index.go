@@ -0,0 +1,186 @@
+package geo
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// Index answers proximity queries over a fixed set of points with a k-d tree.
+//
+// Points are placed on the unit sphere in 3D, where the straight line (chord)
+// distance between two points grows with their great-circle distance. This
+// avoids the special cases of latitude and longitude around the poles and the
+// antimeridian.
+type Index struct {
+	points []Coordinates
+	vecs   []vec3
+
+	// The tree is implicit: the root of each range of tree is at its middle,
+	// splitting along the axis given by its depth.
+	tree []int
+}
+
+type vec3 [3]float64
+
+func toVec3(c Coordinates) vec3 {
+	lat, lon := c.Latitude.ToRadians(), c.Longitude.ToRadians()
+	return vec3{lat.Cos() * lon.Cos(), lat.Cos() * lon.Sin(), lat.Sin()}
+}
+
+func (a vec3) dist2(b vec3) float64 {
+	var d float64
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return d
+}
+
+// Converts a distance on the surface to the squared chord length on the unit
+// sphere.
+func chord2(k Kilometers) float64 {
+	theta := math.Min(float64(k/RadiusOfEarth), math.Pi)
+	c := 2 * math.Sin(theta/2)
+	return c * c
+}
+
+// NewIndex builds an index over points. Queries return indices into points,
+// which must not be modified while the index is in use.
+func NewIndex(points []Coordinates) *Index {
+	ix := &Index{
+		points: points,
+		vecs:   make([]vec3, len(points)),
+		tree:   make([]int, len(points)),
+	}
+	for i, p := range points {
+		ix.vecs[i] = toVec3(p)
+		ix.tree[i] = i
+	}
+	ix.build(ix.tree, 0)
+	return ix
+}
+
+func (ix *Index) build(t []int, depth int) {
+	if len(t) <= 1 {
+		return
+	}
+	axis := depth % 3
+	sort.Slice(t, func(i, j int) bool {
+		return ix.vecs[t[i]][axis] < ix.vecs[t[j]][axis]
+	})
+	mid := len(t) / 2
+	ix.build(t[:mid], depth+1)
+	ix.build(t[mid+1:], depth+1)
+}
+
+// Len returns the number of indexed points.
+func (ix *Index) Len() int {
+	return len(ix.points)
+}
+
+// Nearest returns the indices of the k points closest to c, closest first.
+func (ix *Index) Nearest(c Coordinates, k int) []int {
+	return ix.NearestFunc(c, k, nil)
+}
+
+// NearestFunc is like Nearest but only considers the points whose index keep
+// returns true for, or every point if keep is nil.
+func (ix *Index) NearestFunc(c Coordinates, k int, keep func(i int) bool) []int {
+	if k <= 0 {
+		return nil
+	}
+	q := toVec3(c)
+	h := &candidates{}
+	ix.nearest(ix.tree, 0, q, k, keep, h)
+
+	res := make([]int, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(candidate).i
+	}
+	return res
+}
+
+func (ix *Index) nearest(t []int, depth int, q vec3, k int, keep func(int) bool, h *candidates) {
+	if len(t) == 0 {
+		return
+	}
+	mid := len(t) / 2
+	p := t[mid]
+	// A skipped point still splits the space for its subtrees.
+	if keep == nil || keep(p) {
+		if d := ix.vecs[p].dist2(q); h.Len() < k {
+			heap.Push(h, candidate{p, d})
+		} else if d < (*h)[0].d {
+			(*h)[0] = candidate{p, d}
+			heap.Fix(h, 0)
+		}
+	}
+
+	axis := depth % 3
+	diff := q[axis] - ix.vecs[p][axis]
+	near, far := t[:mid], t[mid+1:]
+	if diff > 0 {
+		near, far = far, near
+	}
+	ix.nearest(near, depth+1, q, k, keep, h)
+	if h.Len() < k || diff*diff < (*h)[0].d {
+		ix.nearest(far, depth+1, q, k, keep, h)
+	}
+}
+
+// Within returns the indices of the points at most r away from c, closest
+// first.
+func (ix *Index) Within(c Coordinates, r Kilometers) []int {
+	q := toVec3(c)
+	var found candidates
+	ix.within(ix.tree, 0, q, chord2(r), &found)
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].d < found[j].d
+	})
+
+	res := make([]int, len(found))
+	for i, f := range found {
+		res[i] = f.i
+	}
+	return res
+}
+
+func (ix *Index) within(t []int, depth int, q vec3, r2 float64, found *candidates) {
+	if len(t) == 0 {
+		return
+	}
+	mid := len(t) / 2
+	p := t[mid]
+	if d := ix.vecs[p].dist2(q); d <= r2 {
+		*found = append(*found, candidate{p, d})
+	}
+
+	axis := depth % 3
+	diff := q[axis] - ix.vecs[p][axis]
+	if diff <= 0 || diff*diff <= r2 {
+		ix.within(t[:mid], depth+1, q, r2, found)
+	}
+	if diff >= 0 || diff*diff <= r2 {
+		ix.within(t[mid+1:], depth+1, q, r2, found)
+	}
+}
+
+type candidate struct {
+	i int
+	d float64
+}
+
+// A max-heap on distance, so that the furthest of the k best candidates so far
+// is the one to replace.
+type candidates []candidate
+
+func (h candidates) Len() int            { return len(h) }
+func (h candidates) Less(i, j int) bool  { return h[i].d > h[j].d }
+func (h candidates) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *candidates) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *candidates) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
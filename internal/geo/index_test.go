@@ -0,0 +1,138 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func randomPoints(r *rand.Rand, n int) []Coordinates {
+	ps := make([]Coordinates, n)
+	for i := range ps {
+		ps[i] = Coordinates{
+			Latitude:  Degrees(r.Float64()*180 - 90),
+			Longitude: Degrees(r.Float64()*360 - 180),
+		}
+	}
+	return ps
+}
+
+// Sorts all the points by distance the slow way.
+func bruteForce(points []Coordinates, c Coordinates) []int {
+	idx := make([]int, len(points))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return c.DistanceTo(points[idx[i]]).Less(c.DistanceTo(points[idx[j]]))
+	})
+	return idx
+}
+
+func TestIndex_Nearest(t *testing.T) {
+	const (
+		points  = 1000
+		queries = 20
+		k       = 10
+	)
+
+	r := rand.New(rand.NewSource(time.Now().Unix()))
+	ps := randomPoints(r, points)
+	ix := NewIndex(ps)
+	if ix.Len() != points {
+		t.Fatalf("got %d points", ix.Len())
+	}
+
+	for _, q := range randomPoints(r, queries) {
+		expected := bruteForce(ps, q)[:k]
+		got := ix.Nearest(q, k)
+		if len(got) != k {
+			t.Fatalf("Expected %d results, got %d", k, len(got))
+		}
+		for i := range got {
+			// Compare distances rather than indices, in case of ties.
+			if q.DistanceTo(ps[got[i]]) != q.DistanceTo(ps[expected[i]]) {
+				t.Errorf("Query %v, rank %d: expected %v, got %v", q, i, ps[expected[i]], ps[got[i]])
+			}
+		}
+	}
+}
+
+func TestIndex_NearestFunc(t *testing.T) {
+	const k = 10
+
+	r := rand.New(rand.NewSource(time.Now().Unix()))
+	ps := randomPoints(r, 1000)
+	ix := NewIndex(ps)
+	even := func(i int) bool { return i%2 == 0 }
+
+	for _, q := range randomPoints(r, 20) {
+		var expected []int
+		for _, i := range bruteForce(ps, q) {
+			if even(i) {
+				expected = append(expected, i)
+			}
+		}
+		got := ix.NearestFunc(q, k, even)
+		if len(got) != k {
+			t.Fatalf("Expected %d results, got %d", k, len(got))
+		}
+		for i := range got {
+			if !even(got[i]) || q.DistanceTo(ps[got[i]]) != q.DistanceTo(ps[expected[i]]) {
+				t.Errorf("Query %v, rank %d: expected %v, got %v", q, i, ps[expected[i]], ps[got[i]])
+			}
+		}
+	}
+}
+
+func TestIndex_Within(t *testing.T) {
+	const radius = Kilometers(2000)
+
+	r := rand.New(rand.NewSource(time.Now().Unix()))
+	ps := randomPoints(r, 1000)
+	ix := NewIndex(ps)
+
+	for _, q := range randomPoints(r, 20) {
+		var expected []int
+		for _, i := range bruteForce(ps, q) {
+			if q.DistanceTo(ps[i]) <= radius {
+				expected = append(expected, i)
+			}
+		}
+		got := ix.Within(q, radius)
+		if len(got) != len(expected) {
+			t.Errorf("Query %v: expected %d points, got %d", q, len(expected), len(got))
+			continue
+		}
+		for i := range got {
+			if q.DistanceTo(ps[got[i]]) != q.DistanceTo(ps[expected[i]]) {
+				t.Errorf("Query %v, rank %d: expected %v, got %v", q, i, ps[expected[i]], ps[got[i]])
+			}
+		}
+	}
+}
+
+func TestIndex_Small(t *testing.T) {
+	if got := NewIndex(nil).Nearest(Coordinates{}, 3); len(got) != 0 {
+		t.Errorf("got: %v", got)
+	}
+	ps := []Coordinates{{Latitude: 0, Longitude: 179}, {Latitude: 0, Longitude: -179}, {Latitude: 0, Longitude: 0}}
+	// Across the antimeridian.
+	if got := NewIndex(ps).Nearest(Coordinates{Latitude: 0, Longitude: -178}, 2); len(got) != 2 || got[0] != 1 || got[1] != 0 {
+		t.Errorf("got: %v", got)
+	}
+	if got := NewIndex(ps).Within(Coordinates{Latitude: 0, Longitude: 180}, 500); len(got) != 2 {
+		t.Errorf("got: %v", got)
+	}
+}
+
+func BenchmarkIndex_Nearest(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	ix := NewIndex(randomPoints(r, 10000))
+	qs := randomPoints(r, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.Nearest(qs[i%len(qs)], 5)
+	}
+}
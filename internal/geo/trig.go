@@ -3,6 +3,8 @@ package geo
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 type Degrees float32
@@ -49,3 +51,25 @@ func (org Coordinates) DistanceTo(dest Coordinates) Kilometers {
 
 	return RadiusOfEarth * Kilometers(c)
 }
+
+func (c Coordinates) String() string {
+	return fmt.Sprintf("%.4f,%.4f", c.Latitude, c.Longitude)
+}
+
+// ParseCoordinates parses coordinates in the "latitude,longitude" form used by
+// Coordinates.String, both in decimal degrees.
+func ParseCoordinates(s string) (Coordinates, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return Coordinates{}, fmt.Errorf("coordinates %q are not of the form latitude,longitude", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 32)
+	if err != nil || lat < -90 || lat > 90 {
+		return Coordinates{}, fmt.Errorf("invalid latitude in %q", s)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 32)
+	if err != nil || lon < -180 || lon > 180 {
+		return Coordinates{}, fmt.Errorf("invalid longitude in %q", s)
+	}
+	return Coordinates{Latitude: Degrees(lat), Longitude: Degrees(lon)}, nil
+}
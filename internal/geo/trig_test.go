@@ -57,3 +57,19 @@ func TestDegrees_ToRadians(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCoordinates(t *testing.T) {
+	c, err := ParseCoordinates("52.3667, -4.9")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.String() != "52.3667,-4.9000" {
+		t.Errorf("got: %v", c)
+	}
+
+	for _, s := range []string{"", "52.3", "91,0", "0,181", "a,b", "1,2,3"} {
+		if _, err := ParseCoordinates(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
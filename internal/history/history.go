@@ -0,0 +1,102 @@
+// Package history keeps the results of past runs in an append-only file of
+// JSON documents, one per line, as written by the json output format.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"framey/assignment/internal/report"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store is a history file.
+type Store struct {
+	Path string
+}
+
+// DefaultPath returns the history file in the user's configuration
+// directory, which unlike the cache directory isn't meant to be wiped.
+func DefaultPath() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "framey-speedtest", "history.jsonl"), nil
+}
+
+// Append adds a result to the history.
+func (s Store) Append(res *report.Result) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	line := append(b, '\n')
+	if cut, err := endsCut(f); err != nil {
+		f.Close()
+		return err
+	} else if cut {
+		// Don't glue this result to the remains of a crashed append.
+		line = append([]byte{'\n'}, line...)
+	}
+	// A single write keeps concurrent appends from interleaving.
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Reports whether f doesn't end with a newline.
+func endsCut(f *os.File) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil || fi.Size() == 0 {
+		return false, err
+	}
+	var last [1]byte
+	if _, err := f.ReadAt(last[:], fi.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// Load returns the results of the runs started in [from, to), in the order
+// they were appended. Zero times leave the range open. A missing file is an
+// empty history, and lines that can't be parsed, such as one cut short by a
+// crash, are skipped.
+func (s Store) Load(from, to time.Time) ([]report.Result, error) {
+	f, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []report.Result
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var r report.Result
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			continue
+		}
+		if !from.IsZero() && r.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !r.Timestamp.Before(to) {
+			continue
+		}
+		results = append(results, r)
+	}
+	return results, sc.Err()
+}
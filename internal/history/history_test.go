@@ -0,0 +1,46 @@
+package history
+
+import (
+	"framey/assignment/internal/report"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s := Store{Path: filepath.Join(t.TempDir(), "sub", "history.jsonl")}
+	if rs, err := s.Load(time.Time{}, time.Time{}); len(rs) != 0 || err != nil {
+		t.Errorf("Expected an empty history, got %v, %v", rs, err)
+	}
+
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		r := report.New(report.Fast)
+		r.Timestamp = start.Add(time.Duration(i) * time.Hour)
+		if err := s.Append(r); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// A crash midway through an append.
+	f, _ := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte(`{"schema_version":1,"prov`))
+	f.Close()
+
+	rs, err := s.Load(start.Add(time.Hour), start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rs) != 1 || !rs[0].Timestamp.Equal(start.Add(time.Hour)) {
+		t.Errorf("got: %+v", rs)
+	}
+
+	if err := s.Append(report.New(report.Speedtest)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rs, _ = s.Load(time.Time{}, time.Time{})
+	if len(rs) != 4 || rs[3].Provider != report.Speedtest {
+		t.Errorf("Expected the whole history, got %d results", len(rs))
+	}
+}
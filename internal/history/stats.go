@@ -0,0 +1,202 @@
+package history
+
+import (
+	"framey/assignment/internal/report"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Summary describes a set of values.
+type Summary struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+}
+
+// Summarize returns the summary of values, using nearest rank percentiles.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	v := append([]float64(nil), values...)
+	sort.Float64s(v)
+	return Summary{
+		Count:  len(v),
+		Min:    v[0],
+		Median: percentile(v, 50),
+		P95:    percentile(v, 95),
+		Max:    v[len(v)-1],
+	}
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Group summarises the runs against a provider, or a single server of it if
+// Server is set. Speeds are in bits per second and latencies in milliseconds.
+type Group struct {
+	Provider string  `json:"provider"`
+	Server   string  `json:"server,omitempty"`
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures"`
+	Download Summary `json:"download_bps"`
+	Upload   Summary `json:"upload_bps"`
+	Latency  Summary `json:"latency_ms"`
+}
+
+// Period summarises the download speeds of a provider in the hours
+// [From, To) of the day.
+type Period struct {
+	Provider string  `json:"provider"`
+	From     int     `json:"from_hour"`
+	To       int     `json:"to_hour"`
+	Download Summary `json:"download_bps"`
+}
+
+// Week summarises the download speeds of a provider over the week starting on
+// the Monday Start, along with the relative change of the median from the
+// previous week with runs, if any.
+type Week struct {
+	Provider string    `json:"provider"`
+	Start    time.Time `json:"start"`
+	Download Summary   `json:"download_bps"`
+	Change   *float64  `json:"change,omitempty"`
+}
+
+// Stats are the statistics over a set of runs.
+type Stats struct {
+	Runs      int      `json:"runs"`
+	Failures  int      `json:"failures"`
+	Providers []Group  `json:"providers"`
+	Servers   []Group  `json:"servers"`
+	TimeOfDay []Period `json:"time_of_day"`
+	Trend     []Week   `json:"trend"`
+}
+
+// Hours of the day that TimeOfDay breaks the day into.
+const periodHours = 6
+
+// Compute returns the statistics of results, with the time of day and weeks
+// taken in loc.
+func Compute(results []report.Result, loc *time.Location) Stats {
+	var (
+		s         Stats
+		providers = make(map[[2]string]*samples)
+		servers   = make(map[[2]string]*samples)
+		periods   = make(map[[2]string]*samples)
+		weeks     = make(map[[2]string]*samples)
+	)
+	for _, r := range results {
+		s.Runs++
+		if r.Error != "" {
+			s.Failures++
+		}
+		t := r.Timestamp.In(loc)
+		add(providers, [2]string{r.Provider}, r)
+		if key := r.ServerKey(); key != "" {
+			add(servers, [2]string{r.Provider, key}, r)
+		}
+		add(periods, [2]string{r.Provider, strconv.Itoa(t.Hour() / periodHours)}, r)
+		add(weeks, [2]string{r.Provider, weekStart(t).Format(time.RFC3339)}, r)
+	}
+
+	group := func(key [2]string, v *samples) Group {
+		return Group{
+			Provider: key[0],
+			Server:   key[1],
+			Runs:     v.runs,
+			Failures: v.failures,
+			Download: Summarize(v.download),
+			Upload:   Summarize(v.upload),
+			Latency:  Summarize(v.latency),
+		}
+	}
+	for _, k := range sortedKeys(providers) {
+		s.Providers = append(s.Providers, group(k, providers[k]))
+	}
+	for _, k := range sortedKeys(servers) {
+		s.Servers = append(s.Servers, group(k, servers[k]))
+	}
+	for _, k := range sortedKeys(periods) {
+		n, _ := strconv.Atoi(k[1])
+		from := n * periodHours
+		s.TimeOfDay = append(s.TimeOfDay, Period{
+			Provider: k[0],
+			From:     from,
+			To:       from + periodHours,
+			Download: Summarize(periods[k].download),
+		})
+	}
+
+	// RFC 3339 times in the same location sort chronologically.
+	var prev Week
+	for _, k := range sortedKeys(weeks) {
+		start, _ := time.ParseInLocation(time.RFC3339, k[1], loc)
+		w := Week{Provider: k[0], Start: start, Download: Summarize(weeks[k].download)}
+		if prev.Provider == w.Provider && prev.Download.Median > 0 && w.Download.Count > 0 {
+			c := w.Download.Median/prev.Download.Median - 1
+			w.Change = &c
+		}
+		s.Trend = append(s.Trend, w)
+		if w.Download.Count > 0 || prev.Provider != w.Provider {
+			prev = w
+		}
+	}
+	return s
+}
+
+// The values seen over a set of runs.
+type samples struct {
+	runs, failures            int
+	download, upload, latency []float64
+}
+
+func add(m map[[2]string]*samples, key [2]string, r report.Result) {
+	v, ok := m[key]
+	if !ok {
+		v = new(samples)
+		m[key] = v
+	}
+	v.runs++
+	if r.Error != "" {
+		v.failures++
+	}
+	if r.Download != nil {
+		v.download = append(v.download, r.Download.BitsPerSecond)
+	}
+	if r.Upload != nil {
+		v.upload = append(v.upload, r.Upload.BitsPerSecond)
+	}
+	if r.Latency != nil {
+		v.latency = append(v.latency, r.Latency.Ms)
+	}
+}
+
+// Returns midnight on the Monday starting the week of t.
+func weekStart(t time.Time) time.Time {
+	days := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-days, 0, 0, 0, 0, t.Location())
+}
+
+func sortedKeys(m map[[2]string]*samples) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"errors"
+	"framey/assignment/internal/report"
+	"testing"
+	"time"
+)
+
+var errInTest = errors.New("test failure")
+
+func TestSummarize(t *testing.T) {
+	var v []float64
+	for i := 20; i > 0; i-- {
+		v = append(v, float64(i))
+	}
+	s := Summarize(v)
+	expected := Summary{Count: 20, Min: 1, Median: 10, P95: 19, Max: 20}
+	if s != expected {
+		t.Errorf("got: %+v, expected: %+v", s, expected)
+	}
+	if s := Summarize(nil); s != (Summary{}) {
+		t.Errorf("got: %+v", s)
+	}
+}
+
+func testResult(provider string, at time.Time, server uint64, download float64) report.Result {
+	r := report.New(provider)
+	r.Timestamp = at
+	if server != 0 {
+		r.Server = &report.Server{ID: server}
+	}
+	r.Latency = &report.Latency{Ms: 10}
+	r.Download = &report.Transfer{BitsPerSecond: download}
+	r.Upload = &report.Transfer{BitsPerSecond: download / 10}
+	return *r
+}
+
+func TestCompute(t *testing.T) {
+	// A Monday.
+	monday := time.Date(2022, 2, 28, 0, 0, 0, 0, time.UTC)
+	failed := report.New(report.Fast)
+	failed.Timestamp = monday.Add(time.Hour)
+	failed.Fail(errInTest)
+
+	s := Compute([]report.Result{
+		testResult(report.Speedtest, monday.Add(2*time.Hour), 1, 100e6),
+		testResult(report.Speedtest, monday.Add(14*time.Hour), 2, 200e6),
+		testResult(report.Speedtest, monday.AddDate(0, 0, 8).Add(2*time.Hour), 1, 75e6),
+		testResult(report.Fast, monday.Add(3*time.Hour), 0, 300e6),
+		*failed,
+	}, time.UTC)
+
+	if s.Runs != 5 || s.Failures != 1 {
+		t.Errorf("Unexpected counts: %d runs, %d failures", s.Runs, s.Failures)
+	}
+	if len(s.Providers) != 2 || s.Providers[0].Provider != report.Fast || s.Providers[0].Failures != 1 {
+		t.Errorf("Unexpected providers: %+v", s.Providers)
+	}
+	if st := s.Providers[1]; st.Download.Min != 75e6 || st.Download.Median != 100e6 || st.Download.Max != 200e6 {
+		t.Errorf("Unexpected speedtest.net download: %+v", st.Download)
+	}
+	if len(s.Servers) != 2 || s.Servers[0].Server != "1" || s.Servers[0].Runs != 2 {
+		t.Errorf("Unexpected servers: %+v", s.Servers)
+	}
+
+	var night, afternoon *Period
+	for i, p := range s.TimeOfDay {
+		if p.Provider != report.Speedtest {
+			continue
+		}
+		switch p.From {
+		case 0:
+			night = &s.TimeOfDay[i]
+		case 12:
+			afternoon = &s.TimeOfDay[i]
+		}
+	}
+	if night == nil || night.Download.Count != 2 || afternoon == nil || afternoon.Download.Median != 200e6 {
+		t.Errorf("Unexpected time of day breakdown: %+v", s.TimeOfDay)
+	}
+
+	var weeks []Week
+	for _, w := range s.Trend {
+		if w.Provider == report.Speedtest {
+			weeks = append(weeks, w)
+		}
+	}
+	if len(weeks) != 2 || !weeks[0].Start.Equal(monday) || weeks[0].Change != nil {
+		t.Fatalf("Unexpected trend: %+v", weeks)
+	}
+	if c := weeks[1].Change; c == nil || *c != -0.25 {
+		t.Errorf("Expected download to drop by a quarter, got %v", c)
+	}
+}
@@ -0,0 +1,229 @@
+// Package metrics keeps gauges, counters and histograms and exposes them in
+// the Prometheus text format, which is all the exporter needs from a
+// Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type kind string
+
+const (
+	gauge     kind = "gauge"
+	counter   kind = "counter"
+	histogram kind = "histogram"
+)
+
+// Registry is a set of metric families. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []*Family
+}
+
+// Family is a metric with a value per combination of label values.
+type Family struct {
+	r       *Registry
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64 // The sum of observations for histograms.
+
+	// Histograms only, counts per bucket, not cumulative.
+	counts []uint64
+	count  uint64
+}
+
+// NewGauge registers a metric that can go up and down.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Family {
+	return r.register(&Family{name: name, help: help, kind: gauge, labels: labels})
+}
+
+// NewCounter registers a metric that only goes up.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Family {
+	return r.register(&Family{name: name, help: help, kind: counter, labels: labels})
+}
+
+// NewHistogram registers a metric counting observations into buckets, given
+// by their increasing upper bounds.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Family {
+	return r.register(&Family{name: name, help: help, kind: histogram, labels: labels, buckets: buckets})
+}
+
+func (r *Registry) register(f *Family) *Family {
+	f.r = r
+	f.series = make(map[string]*series)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+	return f
+}
+
+// Set sets a gauge.
+func (f *Family) Set(v float64, labelValues ...string) {
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	f.get(labelValues).value = v
+}
+
+// Add adds to a counter or gauge.
+func (f *Family) Add(v float64, labelValues ...string) {
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	f.get(labelValues).value += v
+}
+
+// Observe adds an observation to a histogram.
+func (f *Family) Observe(v float64, labelValues ...string) {
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	s := f.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(f.buckets))
+	}
+	for i, b := range f.buckets {
+		if v <= b {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.value += v
+}
+
+// Reset removes every series, for labels whose values change from one
+// observation to the next and would otherwise pile up.
+func (f *Family) Reset() {
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	f.series = make(map[string]*series)
+}
+
+// Delete removes the series whose label is set to value.
+func (f *Family) Delete(label, value string) {
+	i := -1
+	for j, l := range f.labels {
+		if l == label {
+			i = j
+		}
+	}
+	if i < 0 {
+		panic(fmt.Sprintf("metrics: %s has no label %q", f.name, label))
+	}
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	for k, s := range f.series {
+		if s.labels[i] == value {
+			delete(f.series, k)
+		}
+	}
+}
+
+func (f *Family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WriteText writes every metric in the Prometheus text format, series sorted
+// by label values.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range r.families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f.write(bw, f.series[k])
+		}
+	}
+	return bw.Flush()
+}
+
+func (f *Family) write(w io.Writer, s *series) {
+	if f.kind != histogram {
+		fmt.Fprintf(w, "%s%s %s\n", f.name, labelSet(f.labels, s.labels, "", ""), formatValue(s.value))
+		return
+	}
+	var cumulative uint64
+	for i, b := range f.buckets {
+		cumulative += s.counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.labels, "le", formatValue(b)), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.labels, "le", "+Inf"), s.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", f.name, labelSet(f.labels, s.labels, "", ""), formatValue(s.value))
+	fmt.Fprintf(w, "%s_count%s %d\n", f.name, labelSet(f.labels, s.labels, "", ""), s.count)
+}
+
+// Formats labels as {name="value",...}, with an extra label if extraName
+// isn't empty.
+func labelSet(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		pairs = append(pairs, n+`="`+escapeValue(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+escapeValue(extraValue)+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeValue(s string) string { return valueEscaper.Replace(s) }
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
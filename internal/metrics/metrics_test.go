@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	var r Registry
+	g := r.NewGauge("test_speed", "Last speed.", "provider", "server")
+	c := r.NewCounter("test_runs_total", "Runs.")
+	h := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.01, 0.1}, "provider")
+
+	g.Set(2, "fast.com", `say "hi"`)
+	g.Set(1, "fast.com", "a")
+	g.Set(3, "fast.com", "a")
+	c.Add(1)
+	c.Add(1)
+	h.Observe(0.005, "st")
+	h.Observe(0.05, "st")
+	h.Observe(5, "st")
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `# HELP test_speed Last speed.
+# TYPE test_speed gauge
+test_speed{provider="fast.com",server="a"} 3
+test_speed{provider="fast.com",server="say \"hi\""} 2
+# HELP test_runs_total Runs.
+# TYPE test_runs_total counter
+test_runs_total 2
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{provider="st",le="0.01"} 1
+test_latency_seconds_bucket{provider="st",le="0.1"} 2
+test_latency_seconds_bucket{provider="st",le="+Inf"} 3
+test_latency_seconds_sum{provider="st"} 5.055
+test_latency_seconds_count{provider="st"} 3
+`
+	if buf.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFamily_WrongLabels(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic")
+		}
+	}()
+	var r Registry
+	r.NewGauge("test", "Test.", "provider").Set(1)
+}
+
+func TestFamily_Reset(t *testing.T) {
+	var r Registry
+	g := r.NewGauge("test_info", "Info.", "server")
+	g.Set(1, "a")
+	g.Reset()
+	g.Set(1, "b")
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `# HELP test_info Info.
+# TYPE test_info gauge
+test_info{server="b"} 1
+`
+	if buf.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFamily_Delete(t *testing.T) {
+	var r Registry
+	c := r.NewCounter("test_bytes_total", "Bytes.", "server", "direction")
+	c.Add(1, "a", "down")
+	c.Add(2, "a", "up")
+	c.Add(3, "b", "down")
+	c.Delete("server", "a")
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `# HELP test_bytes_total Bytes.
+# TYPE test_bytes_total counter
+test_bytes_total{server="b",direction="down"} 3
+`
+	if buf.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
@@ -0,0 +1,27 @@
+package oututil
+
+import (
+	"fmt"
+	"framey/assignment/pkg/timing"
+	"time"
+)
+
+// PrintTimings prints the per-phase request timings collected by r. Nothing is
+// printed for a nil recorder.
+func PrintTimings(r *timing.Recorder) {
+	if r == nil {
+		return
+	}
+	for _, p := range timing.Phases {
+		s := r.Stats(p)
+		if s.Count == 0 {
+			continue
+		}
+		fmt.Printf("  %-8s %5d × avg %8s  min %8s  max %8s\n",
+			p, s.Count, ms(s.Mean()), ms(s.Min), ms(s.Max))
+	}
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond))
+}
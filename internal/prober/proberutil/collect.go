@@ -6,10 +6,13 @@ import (
 	"time"
 )
 
+// SpeedCollect waits for the probes in grp and returns their average speed
+// and how much they transferred, streaming the speed so far if stream isn't
+// nil.
 func SpeedCollect(
 	grp *prober.Group,
 	stream chan<- units.BytesPerSecond,
-) (units.BytesPerSecond, error) {
+) (units.BytesPerSecond, prober.BytesTransferred, error) {
 	start := time.Now()
 
 	if stream != nil {
@@ -25,9 +28,9 @@ func SpeedCollect(
 
 	b, err := grp.Collect()
 	if err != nil {
-		return units.BytesPerSecond(0), err
+		return units.BytesPerSecond(0), b, err
 	} else {
 		d := float64(time.Since(start)) / float64(time.Second)
-		return units.BytesPerSecond(float64(b) / d), nil
+		return units.BytesPerSecond(float64(b) / d), b, nil
 	}
 }
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{
+	"timestamp",
+	"provider",
+	"server_id",
+	"server",
+	"distance_km",
+	"latency_ms",
+	"jitter_ms",
+	"download_bps",
+	"upload_bps",
+	"download_bytes",
+	"upload_bytes",
+	"isp",
+	"ip",
+	"error",
+	"proxy",
+}
+
+// WriteCSVHeader writes the names of the columns written by WriteCSV.
+func WriteCSVHeader(w io.Writer) error {
+	return writeCSV(w, csvHeader)
+}
+
+// WriteCSV writes r as a single CSV record. The server column holds the
+// speedtest.net server's sponsor and name, or the names of the fast.com
+// targets used, separated by semicolons. Unknown values are left empty.
+func (r *Result) WriteCSV(w io.Writer) error {
+	rec := make([]string, 0, len(csvHeader))
+	rec = append(rec, r.Timestamp.Format(time.RFC3339), r.Provider)
+
+	switch {
+	case r.Server != nil:
+		rec = append(rec,
+			strconv.FormatUint(r.Server.ID, 10),
+			r.Server.Sponsor+" ("+r.Server.Name+")",
+			formatFloat(r.Server.DistanceKm))
+	case r.Targets != nil:
+		var names []string
+		for _, t := range r.Targets {
+			if !t.Skipped {
+				names = append(names, t.Name)
+			}
+		}
+		rec = append(rec, "", strings.Join(names, ";"), "")
+	default:
+		rec = append(rec, "", "", "")
+	}
+
+	if r.Latency != nil {
+		rec = append(rec, formatFloat(r.Latency.Ms), formatFloat(r.Latency.JitterMs))
+	} else {
+		rec = append(rec, "", "")
+	}
+
+	var dl, ul, dlBytes, ulBytes string
+	if r.Download != nil {
+		dl, dlBytes = formatFloat(r.Download.BitsPerSecond), strconv.FormatInt(r.Download.Bytes, 10)
+	}
+	if r.Upload != nil {
+		ul, ulBytes = formatFloat(r.Upload.BitsPerSecond), strconv.FormatInt(r.Upload.Bytes, 10)
+	}
+	rec = append(rec, dl, ul, dlBytes, ulBytes, r.Client.ISP, r.Client.IP, r.Error, r.Proxy)
+	return writeCSV(w, rec)
+}
+
+func writeCSV(w io.Writer, rec []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(rec); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
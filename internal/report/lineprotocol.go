@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ServerKey identifies the server tested against: the speedtest.net server ID
+// or the name of the best fast.com target. It is empty if no server was
+// chosen.
+func (r *Result) ServerKey() string {
+	if r.Server != nil {
+		return strconv.FormatUint(r.Server.ID, 10)
+	}
+	for _, t := range r.Targets {
+		if !t.Skipped {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// WriteLineProtocol writes r as a single point in the InfluxDB line protocol,
+// tagged by provider, server, ISP, proxy and the extra tags given. Fields are only
+// written for what was measured, along with whether the run succeeded and its
+// error, if any.
+func (r *Result) WriteLineProtocol(w io.Writer, measurement string, tags map[string]string) error {
+	all := map[string]string{
+		"provider":  r.Provider,
+		"server_id": r.ServerKey(),
+		"isp":       r.Client.ISP,
+		"proxy":     r.Proxy,
+	}
+	for k, v := range tags {
+		all[k] = v
+	}
+	keys := make([]string, 0, len(all))
+	for k, v := range all {
+		// Empty tag values aren't allowed.
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	// InfluxDB prefers tags sorted by key.
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(measurement))
+	for _, k := range keys {
+		fmt.Fprintf(&b, ",%s=%s", tagEscaper.Replace(k), tagEscaper.Replace(all[k]))
+	}
+
+	fields := []string{"success=" + strconv.FormatBool(r.Error == "")}
+	if r.Latency != nil {
+		fields = append(fields,
+			"latency_ms="+formatFloat(r.Latency.Ms),
+			"jitter_ms="+formatFloat(r.Latency.JitterMs))
+	}
+	if r.Download != nil {
+		fields = append(fields,
+			"download_bps="+formatFloat(r.Download.BitsPerSecond),
+			"download_bytes="+strconv.FormatInt(r.Download.Bytes, 10)+"i")
+	}
+	if r.Upload != nil {
+		fields = append(fields,
+			"upload_bps="+formatFloat(r.Upload.BitsPerSecond),
+			"upload_bytes="+strconv.FormatInt(r.Upload.Bytes, 10)+"i")
+	}
+	if r.Error != "" {
+		fields = append(fields, `error="`+fieldEscaper.Replace(r.Error)+`"`)
+	}
+	fmt.Fprintf(&b, " %s %d\n", strings.Join(fields, ","), r.Timestamp.UnixNano())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	fieldEscaper       = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
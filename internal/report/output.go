@@ -0,0 +1,60 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Machine readable output formats.
+const (
+	JSON = "json"
+	CSV  = "csv"
+)
+
+// Write writes r in the given format: JSON or CSV. A CSV header line is
+// written first if header is set.
+func (r *Result) Write(w io.Writer, format string, header bool) error {
+	switch format {
+	case JSON:
+		return r.WriteJSON(w)
+	case CSV:
+		if header {
+			if err := WriteCSVHeader(w); err != nil {
+				return err
+			}
+		}
+		return r.WriteCSV(w)
+	default:
+		return fmt.Errorf("unknown output format: %q", format)
+	}
+}
+
+// Open opens the file results are written to, truncating it unless
+// appending, and reports whether it is empty, in which case a CSV header is
+// due. An empty path means standard output, which never gets a header.
+func Open(path string, append bool) (w io.WriteCloser, empty bool, err error) {
+	if path == "" {
+		return nopCloser{os.Stdout}, false, nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	return f, fi.Size() == 0, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
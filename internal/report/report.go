@@ -0,0 +1,169 @@
+// Package report describes the outcome of a test run in the same shape for
+// both providers, for the machine readable output formats.
+//
+// Field names are part of the output and must stay stable. Anything that
+// changes their meaning or removes one requires bumping SchemaVersion.
+package report
+
+import (
+	"encoding/json"
+	"framey/assignment/internal/units"
+	"framey/assignment/pkg/timing"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the documents written by WriteJSON.
+const SchemaVersion = 1
+
+// Providers.
+const (
+	Speedtest = "speedtest.net"
+	Fast      = "fast.com"
+)
+
+// Result is everything known about a run. Parts that weren't reached because
+// of an error are left out.
+type Result struct {
+	SchemaVersion int       `json:"schema_version"`
+	Provider      string    `json:"provider"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	Client Client `json:"client"`
+
+	// Proxy is the proxy the test went through, if any, with any password
+	// redacted, since it changes what was measured.
+	Proxy string `json:"proxy,omitempty"`
+
+	Server  *Server  `json:"server,omitempty"`
+	Targets []Target `json:"targets,omitempty"`
+
+	Latency  *Latency  `json:"latency,omitempty"`
+	Download *Transfer `json:"download,omitempty"`
+	Upload   *Transfer `json:"upload,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	// Breaches lists the thresholds that a successful run fell short of.
+	Breaches []string `json:"breaches,omitempty"`
+}
+
+// New returns an empty result for a run against provider starting now.
+func New(provider string) *Result {
+	return &Result{
+		SchemaVersion: SchemaVersion,
+		Provider:      provider,
+		Timestamp:     time.Now().UTC(),
+	}
+}
+
+// Fail records the error that ended the run, if any.
+func (r *Result) Fail(err error) {
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Client is the machine being tested, as seen by the provider.
+type Client struct {
+	IP  string `json:"ip,omitempty"`
+	ISP string `json:"isp,omitempty"`
+	ASN string `json:"asn,omitempty"`
+
+	// Location is a place name for fast.com, Latitude and Longitude are set
+	// for speedtest.net along with where they came from.
+	Location       string   `json:"location,omitempty"`
+	Latitude       *float64 `json:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty"`
+	LocationSource string   `json:"location_source,omitempty"`
+}
+
+// Server is the speedtest.net server tested against.
+type Server struct {
+	ID         uint64  `json:"id"`
+	Name       string  `json:"name"`
+	Sponsor    string  `json:"sponsor"`
+	Country    string  `json:"country"`
+	CC         string  `json:"cc"`
+	Host       string  `json:"host"`
+	URL        string  `json:"url"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// Target is a fast.com server, which may have been left out of the speed
+// probes for its latency.
+type Target struct {
+	Name      string  `json:"name"`
+	URL       string  `json:"url"`
+	Location  string  `json:"location"`
+	LatencyMs float64 `json:"latency_ms"`
+	Skipped   bool    `json:"skipped,omitempty"`
+}
+
+// Latency is the unloaded latency of the best server.
+type Latency struct {
+	Ms       float64 `json:"ms"`
+	JitterMs float64 `json:"jitter_ms,omitempty"`
+}
+
+// Transfer is the outcome of a download or upload probe.
+type Transfer struct {
+	BitsPerSecond float64 `json:"bits_per_second"`
+
+	// Bytes is how much the probe requests transferred.
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+
+	Timings map[string]PhaseTimings `json:"timings,omitempty"`
+}
+
+// NewTransfer describes a probe that achieved speed over d transferring bytes,
+// with the request timings collected by r, which may be nil.
+func NewTransfer(speed units.BytesPerSecond, bytes int64, d time.Duration, r *timing.Recorder) *Transfer {
+	return &Transfer{
+		BitsPerSecond: float64(speed.BitsPerSecond()),
+		Bytes:         bytes,
+		DurationMs:    Ms(d),
+		Timings:       Timings(r),
+	}
+}
+
+// PhaseTimings summarises the durations of one phase of the probe requests.
+type PhaseTimings struct {
+	Count  int     `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	MinMs  float64 `json:"min_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// Timings returns the phases recorded by r by name, or nil for a nil
+// recorder.
+func Timings(r *timing.Recorder) map[string]PhaseTimings {
+	if r == nil {
+		return nil
+	}
+	m := make(map[string]PhaseTimings)
+	for _, p := range timing.Phases {
+		s := r.Stats(p)
+		if s.Count == 0 {
+			continue
+		}
+		m[p.String()] = PhaseTimings{
+			Count:  s.Count,
+			MeanMs: Ms(s.Mean()),
+			MinMs:  Ms(s.Min),
+			MaxMs:  Ms(s.Max),
+		}
+	}
+	return m
+}
+
+// Ms converts d to fractional milliseconds.
+func Ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteJSON writes r as a single line JSON document.
+func (r *Result) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
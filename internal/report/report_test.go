@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"framey/assignment/internal/units"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResult_WriteJSON(t *testing.T) {
+	r := New(Speedtest)
+	r.Server = &Server{ID: 42, Sponsor: "Example"}
+	r.Download = NewTransfer(units.MBps, 2000000, 2*time.Second, nil)
+	r.Fail(errors.New("upload failed"))
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if doc["schema_version"] != float64(SchemaVersion) || doc["provider"] != Speedtest {
+		t.Errorf("got: %s", buf.Bytes())
+	}
+	if _, ok := doc["upload"]; ok {
+		t.Errorf("Expected no upload, got: %s", buf.Bytes())
+	}
+	dl := doc["download"].(map[string]interface{})
+	if dl["bits_per_second"] != 8e6 || dl["bytes"] != 2e6 || dl["duration_ms"] != 2000.0 {
+		t.Errorf("Unexpected download: %v", dl)
+	}
+	if doc["error"] != "upload failed" {
+		t.Errorf("Unexpected error field: %v", doc["error"])
+	}
+}
+
+func TestResult_WriteCSV(t *testing.T) {
+	r := New(Fast)
+	r.Timestamp = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.Client = Client{ISP: "Test, Inc.", IP: "192.0.2.1"}
+	r.Proxy = "socks5://proxy:1080"
+	r.Targets = []Target{{Name: "a"}, {Name: "b"}, {Name: "c", Skipped: true}}
+	r.Latency = &Latency{Ms: 12.5}
+	r.Download = NewTransfer(units.MBps, 1000000, time.Second, nil)
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf, CSV, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "timestamp,provider,server_id,server,distance_km,latency_ms,jitter_ms," +
+		"download_bps,upload_bps,download_bytes,upload_bytes,isp,ip,error,proxy\n" +
+		"2022-03-01T12:00:00Z,fast.com,,a;b,,12.5,0,8000000,,1000000,,\"Test, Inc.\",192.0.2.1,,socks5://proxy:1080\n"
+	if buf.String() != expected {
+		t.Errorf("got: %q, expected: %q", buf.String(), expected)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	for i, expected := range []bool{true, false} {
+		w, empty, err := Open(path, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if empty != expected {
+			t.Errorf("Open %d reported empty: %v", i, empty)
+		}
+		w.Write([]byte("line\n"))
+		w.Close()
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "line\nline\n" {
+		t.Errorf("Expected two appended lines, got %q", b)
+	}
+
+	w, empty, err := Open(path, false)
+	if err != nil || !empty {
+		t.Errorf("Expected the file to be truncated, got %v, %v", empty, err)
+	}
+	w.Close()
+}
+
+func TestResult_WriteLineProtocol(t *testing.T) {
+	r := New(Speedtest)
+	r.Timestamp = time.Unix(1646136000, 0)
+	r.Client = Client{ISP: "Test ISP, Inc."}
+	r.Server = &Server{ID: 42}
+	r.Proxy = "http://proxy:3128"
+	r.Latency = &Latency{Ms: 12.5, JitterMs: 1}
+	r.Download = NewTransfer(units.MBps, 1000000, time.Second, nil)
+	r.Fail(errors.New(`upload "failed"`))
+
+	var buf bytes.Buffer
+	if err := r.WriteLineProtocol(&buf, "netspeed", map[string]string{"host": "box", "empty": ""}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `netspeed,host=box,isp=Test\ ISP\,\ Inc.,provider=speedtest.net,proxy=http://proxy:3128,server_id=42 ` +
+		`success=false,latency_ms=12.5,jitter_ms=1,download_bps=8000000,download_bytes=1000000i,` +
+		`error="upload \"failed\"" 1646136000000000000` + "\n"
+	if buf.String() != expected {
+		t.Errorf("got: %q, expected: %q", buf.String(), expected)
+	}
+}
+
+func TestThresholds_Check(t *testing.T) {
+	th := Thresholds{MinDownload: 100 * units.Mbps, MinUpload: 10 * units.Mbps, MaxLatency: 25 * time.Millisecond}
+
+	r := New(Fast)
+	r.Latency = &Latency{Ms: 30}
+	r.Download = &Transfer{BitsPerSecond: 50e6}
+	r.Upload = &Transfer{BitsPerSecond: 20e6}
+	th.Check(r)
+	if len(r.Breaches) != 2 {
+		t.Errorf("Expected the download and latency to breach, got %q", r.Breaches)
+	}
+
+	failed := New(Fast)
+	failed.Fail(errors.New("no network"))
+	th.Check(failed)
+	if len(failed.Breaches) != 0 {
+		t.Errorf("Expected a failed run not to be checked, got %q", failed.Breaches)
+	}
+}
@@ -0,0 +1,35 @@
+package report
+
+import (
+	"fmt"
+	"framey/assignment/internal/units"
+	"time"
+)
+
+// Thresholds are the limits a result must stay within. Zero values aren't
+// checked.
+type Thresholds struct {
+	MinDownload units.BitsPerSecond
+	MinUpload   units.BitsPerSecond
+	MaxLatency  time.Duration
+}
+
+// Check records in r every threshold that it breaches. Results of failed runs
+// aren't checked, their error says it all.
+func (t Thresholds) Check(r *Result) {
+	if r.Error != "" {
+		return
+	}
+	if t.MinDownload > 0 && r.Download != nil && units.BitsPerSecond(r.Download.BitsPerSecond) < t.MinDownload {
+		r.Breaches = append(r.Breaches, fmt.Sprintf("download speed %v is below the minimum of %v",
+			units.BitsPerSecond(r.Download.BitsPerSecond), t.MinDownload))
+	}
+	if t.MinUpload > 0 && r.Upload != nil && units.BitsPerSecond(r.Upload.BitsPerSecond) < t.MinUpload {
+		r.Breaches = append(r.Breaches, fmt.Sprintf("upload speed %v is below the minimum of %v",
+			units.BitsPerSecond(r.Upload.BitsPerSecond), t.MinUpload))
+	}
+	if t.MaxLatency > 0 && r.Latency != nil && r.Latency.Ms > Ms(t.MaxLatency) {
+		r.Breaches = append(r.Breaches, fmt.Sprintf("latency %.1f ms is above the maximum of %v",
+			r.Latency.Ms, t.MaxLatency))
+	}
+}
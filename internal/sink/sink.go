@@ -0,0 +1,147 @@
+// Package sink pushes the results of test runs to other systems.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"framey/assignment/internal/report"
+	"framey/assignment/pkg/retry"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Sink receives the result of every run.
+type Sink interface {
+	Send(ctx context.Context, res *report.Result) error
+}
+
+// Encoder writes a result in some format.
+type Encoder func(w io.Writer, res *report.Result) error
+
+// LineProtocol encodes results as InfluxDB points of the given measurement,
+// tagged with the host name.
+func LineProtocol(measurement string) Encoder {
+	host, _ := os.Hostname()
+	return func(w io.Writer, res *report.Result) error {
+		return res.WriteLineProtocol(w, measurement, map[string]string{"host": host})
+	}
+}
+
+// Writer writes results to W, e.g. standard output.
+type Writer struct {
+	W      io.Writer
+	Encode Encoder
+}
+
+func (s Writer) Send(_ context.Context, res *report.Result) error {
+	return s.Encode(s.W, res)
+}
+
+// File appends results to the file at Path, which is created if needed.
+type File struct {
+	Path   string
+	Encode Encoder
+}
+
+func (s File) Send(_ context.Context, res *report.Result) error {
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := s.Encode(f, res); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// HTTP posts results to URL, retrying transient failures according to the
+// policy carried by the context. Any 2xx status is a success.
+type HTTP struct {
+	URL         string
+	ContentType string
+	Header      http.Header
+	Encode      Encoder
+
+	// Client makes the requests. Nil means http.DefaultClient.
+	Client *http.Client
+}
+
+func (s HTTP) Send(ctx context.Context, res *report.Result) error {
+	var body bytes.Buffer
+	if err := s.Encode(&body, res); err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return retry.FromContext(ctx).Do(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return err
+		}
+		for k, v := range s.Header {
+			req.Header[k] = v
+		}
+		if s.ContentType != "" {
+			req.Header.Set("Content-Type", s.ContentType)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+		if resp.StatusCode/100 != 2 {
+			return &retry.StatusError{URL: s.URL, Code: resp.StatusCode}
+		}
+		return nil
+	})
+}
+
+// Multi sends results to every sink, even if some fail.
+type Multi []Sink
+
+func (m Multi) Send(ctx context.Context, res *report.Result) error {
+	var errs []string
+	for _, s := range m {
+		if err := s.Send(ctx, res); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("sink: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Influx returns a sink writing InfluxDB line protocol to dest: standard output
+// for "-", an HTTP write endpoint for http and https URLs, such as
+// "http://localhost:8086/api/v2/write?org=o&bucket=b", and a file otherwise.
+// A non-empty token authorises the writes to the endpoint.
+func Influx(dest, token string) Sink {
+	encode := LineProtocol("netspeed")
+	switch {
+	case dest == "-":
+		return Writer{W: os.Stdout, Encode: encode}
+	case strings.HasPrefix(dest, "http://"), strings.HasPrefix(dest, "https://"):
+		h := make(http.Header)
+		if token != "" {
+			h.Set("Authorization", "Token "+token)
+		}
+		return HTTP{
+			URL:         dest,
+			ContentType: "text/plain; charset=utf-8",
+			Header:      h,
+			Encode:      encode,
+		}
+	default:
+		return File{Path: dest, Encode: encode}
+	}
+}
@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"framey/assignment/internal/report"
+	"framey/assignment/pkg/retry"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = retry.Policy{
+	Attempts:        3,
+	BaseDelay:       time.Millisecond,
+	MaxDelay:        time.Millisecond,
+	RetryableStatus: []int{http.StatusServiceUnavailable},
+}
+
+func encodeProvider(w io.Writer, res *report.Result) error {
+	_, err := io.WriteString(w, res.Provider+"\n")
+	return err
+}
+
+func TestFile_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results")
+	s := File{Path: path, Encode: encodeProvider}
+	for _, p := range []string{report.Speedtest, report.Fast} {
+		if err := s.Send(context.Background(), report.New(p)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "speedtest.net\nfast.com\n" {
+		t.Errorf("got: %q", b)
+	}
+}
+
+func TestHTTP_Send(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Token secret" || !strings.HasPrefix(string(b), "netspeed,") {
+			t.Errorf("Unexpected request: %v %q", r.Header, b)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	ctx := retry.WithPolicy(context.Background(), testRetryPolicy)
+	if err := Influx(ts.URL, "secret").Send(ctx, report.New(report.Fast)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected a retry, got %d calls", n)
+	}
+}
+
+func TestHTTP_Send_Rejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	ctx := retry.WithPolicy(context.Background(), testRetryPolicy)
+	if err := Influx(ts.URL, "").Send(ctx, report.New(report.Fast)); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func TestMulti_Send(t *testing.T) {
+	var a, b bytes.Buffer
+	m := Multi{
+		File{Path: filepath.Join(t.TempDir(), "missing", "file"), Encode: encodeProvider},
+		Writer{W: &a, Encode: encodeProvider},
+		Writer{W: &b, Encode: encodeProvider},
+	}
+	if err := m.Send(context.Background(), report.New(report.Fast)); err == nil {
+		t.Errorf("Expected the failing file to be reported")
+	}
+	if a.String() != "fast.com\n" || b.String() != "fast.com\n" {
+		t.Errorf("Expected every writer to be sent to despite the error, got %q and %q", a.String(), b.String())
+	}
+}
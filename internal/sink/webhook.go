@@ -0,0 +1,165 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"framey/assignment/internal/report"
+	"framey/assignment/internal/units"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Statuses of an Alert.
+const (
+	Failed         = "failed"
+	BelowThreshold = "below_threshold"
+	Recovered      = "recovered"
+)
+
+// Alert is what a webhook is told about a result that needs attention.
+type Alert struct {
+	Status string         `json:"status"`
+	Title  string         `json:"title"`
+	Reason string         `json:"reason"`
+	Host   string         `json:"host"`
+	Result *report.Result `json:"result"`
+}
+
+// Builtin webhook payloads: the alert itself, and messages for Slack and
+// Microsoft Teams incoming webhooks.
+var webhookTemplates = map[string]string{
+	"json":  `{{json .}}`,
+	"slack": `{"text":{{json (printf "*%s*\n%s" .Title .Reason)}}}`,
+	"teams": `{"@type":"MessageCard","@context":"https://schema.org/extensions",` +
+		`"themeColor":{{if eq .Status "recovered"}}"2EB886"{{else}}"D00000"{{end}},` +
+		`"summary":{{json .Title}},"title":{{json .Title}},"text":{{json .Reason}}}`,
+}
+
+// WebhookTemplate returns the builtin payload template called name, json,
+// slack or teams, or else parses the text/template file at name. Templates
+// are executed with an Alert and have a json function to quote values.
+func WebhookTemplate(name string) (*template.Template, error) {
+	t := template.New(name).Funcs(template.FuncMap{"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}})
+	if text, ok := webhookTemplates[name]; ok {
+		return t.Parse(text)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook template: %v", err)
+	}
+	return t.Parse(string(b))
+}
+
+// Webhook posts an Alert to URL when a run fails or breaches a threshold, and
+// when runs of the provider succeed again. A problem that persists is only
+// posted about again after Repeat, or never if Repeat is zero, so that an
+// outage doesn't post every run. Failed posts are retried like HTTP's, and
+// then on the next run.
+type Webhook struct {
+	URL      string
+	Template *template.Template
+	Repeat   time.Duration
+
+	// Client makes the requests. Nil means http.DefaultClient.
+	Client *http.Client
+
+	mu     sync.Mutex
+	posted map[string]posted // by provider
+}
+
+// What was last posted about a provider.
+type posted struct {
+	status string
+	at     time.Time
+}
+
+func (s *Webhook) Send(ctx context.Context, res *report.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := alertStatus(res)
+	last := s.posted[res.Provider]
+	switch {
+	case status == "":
+		if last.status == "" || last.status == Recovered {
+			return nil
+		}
+		status = Recovered
+	case status == last.status:
+		if s.Repeat <= 0 || time.Since(last.at) < s.Repeat {
+			return nil
+		}
+	}
+
+	a := newAlert(status, res)
+	h := HTTP{
+		URL:         s.URL,
+		ContentType: "application/json",
+		Encode: func(w io.Writer, _ *report.Result) error {
+			return s.Template.Execute(w, a)
+		},
+		Client: s.Client,
+	}
+	if err := h.Send(ctx, res); err != nil {
+		return err
+	}
+	if s.posted == nil {
+		s.posted = make(map[string]posted)
+	}
+	s.posted[res.Provider] = posted{status, time.Now()}
+	return nil
+}
+
+// Returns the status of an alert about res, or "" if it needs none.
+func alertStatus(res *report.Result) string {
+	switch {
+	case res.Error != "":
+		return Failed
+	case len(res.Breaches) > 0:
+		return BelowThreshold
+	default:
+		return ""
+	}
+}
+
+func newAlert(status string, res *report.Result) Alert {
+	host, _ := os.Hostname()
+	a := Alert{Status: status, Host: host, Result: res}
+	switch status {
+	case Failed:
+		a.Title = fmt.Sprintf("The %s test failed on %s", res.Provider, host)
+		a.Reason = res.Error
+	case BelowThreshold:
+		a.Title = fmt.Sprintf("The %s test was below the thresholds on %s", res.Provider, host)
+		a.Reason = strings.Join(res.Breaches, "; ")
+	case Recovered:
+		a.Title = fmt.Sprintf("The %s test recovered on %s", res.Provider, host)
+		a.Reason = summary(res)
+	}
+	return a
+}
+
+// Describes a successful result in a line.
+func summary(res *report.Result) string {
+	var parts []string
+	if res.Download != nil {
+		parts = append(parts, fmt.Sprintf("download %v", units.BitsPerSecond(res.Download.BitsPerSecond)))
+	}
+	if res.Upload != nil {
+		parts = append(parts, fmt.Sprintf("upload %v", units.BitsPerSecond(res.Upload.BitsPerSecond)))
+	}
+	if res.Latency != nil {
+		parts = append(parts, fmt.Sprintf("latency %.1f ms", res.Latency.Ms))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,108 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"framey/assignment/internal/report"
+	"framey/assignment/pkg/retry"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newWebhookTestServer(t *testing.T, alerts chan<- Alert) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		alerts <- a
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestWebhook_Send(t *testing.T) {
+	alerts := make(chan Alert, 10)
+	ts := newWebhookTestServer(t, alerts)
+	tmpl, err := WebhookTemplate("json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := &Webhook{URL: ts.URL, Template: tmpl}
+
+	ok := report.New(report.Fast)
+	failed := report.New(report.Fast)
+	failed.Fail(errors.New("no network"))
+	slow := report.New(report.Fast)
+	slow.Breaches = []string{"download speed is below the minimum"}
+
+	for _, res := range []*report.Result{ok, failed, failed, slow, slow, ok, ok} {
+		if err := s.Send(context.Background(), res); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	close(alerts)
+
+	var got []string
+	for a := range alerts {
+		got = append(got, a.Status+": "+a.Reason)
+	}
+	expected := []string{"failed: no network", "below_threshold: download speed is below the minimum", "recovered: "}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], got[i])
+		}
+	}
+}
+
+func TestWebhook_Send_RetriesNextRun(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+	tmpl, _ := WebhookTemplate("slack")
+	s := &Webhook{URL: ts.URL, Template: tmpl}
+
+	failed := report.New(report.Speedtest)
+	failed.Fail(errors.New("no network"))
+	ctx := retry.WithPolicy(context.Background(), testRetryPolicy)
+	if err := s.Send(ctx, failed); err == nil {
+		t.Errorf("Expected the rejected post to be reported")
+	}
+	if err := s.Send(ctx, failed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the alert to be posted again, got %d calls", calls)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	res := report.New(report.Speedtest)
+	res.Fail(errors.New(`unexpected "quote"`))
+	a := newAlert(Failed, res)
+
+	for _, name := range []string{"json", "slack", "teams"} {
+		tmpl, err := WebhookTemplate(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, a); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !json.Valid(b.Bytes()) {
+			t.Errorf("Invalid %s payload: %s", name, b.String())
+		}
+	}
+}
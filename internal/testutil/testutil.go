@@ -0,0 +1,23 @@
+// Package testutil holds the fixtures shared by the tests of several
+// packages.
+package testutil
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// RedirectTransport sends every request to the server at its base URL
+// regardless of the request's scheme and host, e.g. to stand in for a
+// provider with an httptest server.
+type RedirectTransport string
+
+func (base RedirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, err := url.Parse(string(base))
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
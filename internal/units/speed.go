@@ -2,6 +2,8 @@ package units
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 type BytesPerSecond float64
@@ -48,3 +50,40 @@ func (s BitsPerSecond) String() string {
 		return fmt.Sprintf("%.02f Gb/s", s/Gbps)
 	}
 }
+
+var speedUnits = []struct {
+	suffix string
+	bits   BitsPerSecond
+}{
+	{"Gbps", Gbps}, {"Gb/s", Gbps},
+	{"Mbps", Mbps}, {"Mb/s", Mbps},
+	{"Kbps", Kbps}, {"Kb/s", Kbps}, {"kbps", Kbps}, {"kb/s", Kbps},
+	{"bps", 1}, {"b/s", 1},
+	{"GBps", BitsPerSecond(GBps * 8)}, {"GB/s", BitsPerSecond(GBps * 8)},
+	{"MBps", BitsPerSecond(MBps * 8)}, {"MB/s", BitsPerSecond(MBps * 8)},
+	{"KBps", BitsPerSecond(KBps * 8)}, {"KB/s", BitsPerSecond(KBps * 8)}, {"kB/s", BitsPerSecond(KBps * 8)},
+	{"Bps", 8}, {"B/s", 8},
+}
+
+// ParseBitsPerSecond parses a speed such as "100Mbps", "1.5 Gb/s" or
+// "12.5MB/s". Units are case sensitive since "b" is bits and "B" is bytes.
+func ParseBitsPerSecond(s string) (BitsPerSecond, error) {
+	s = strings.TrimSpace(s)
+	for _, u := range speedUnits {
+		if !strings.HasSuffix(s, u.suffix) {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), 64)
+		if err != nil || n < 0 {
+			break
+		}
+		return BitsPerSecond(n) * u.bits, nil
+	}
+	return 0, fmt.Errorf("invalid speed %q, expected e.g. 100Mbps or 12.5MB/s", s)
+}
+
+// Set parses a speed for the flag package.
+func (s *BitsPerSecond) Set(v string) (err error) {
+	*s, err = ParseBitsPerSecond(v)
+	return
+}
@@ -0,0 +1,26 @@
+package units
+
+import "testing"
+
+func TestParseBitsPerSecond(t *testing.T) {
+	for s, expected := range map[string]BitsPerSecond{
+		"100Mbps":   100 * Mbps,
+		"1.5 Gb/s":  1.5 * Gbps,
+		"12.5MB/s":  100 * Mbps,
+		"64kbps":    64 * Kbps,
+		" 800 bps ": 800,
+	} {
+		got, err := ParseBitsPerSecond(s)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", s, err)
+		} else if got != expected {
+			t.Errorf("%q: got %v, expected %v", s, got, expected)
+		}
+	}
+
+	for _, s := range []string{"", "100", "fast Mbps", "-1Mbps", "100mbps"} {
+		if _, err := ParseBitsPerSecond(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package cassette records the discovery traffic of the speedtest.net and
+// fast.com clients to a file and replays it, so that the full flow can be
+// tested without reaching the real services.
+//
+// Recording:
+//
+//	rec := &cassette.Recorder{}
+//	client := &speedtest.Client{Transport: rec}
+//	// ... use client ...
+//	err := rec.Cassette().Save("testdata/speedtest.json")
+//
+// Replaying:
+//
+//	c, err := cassette.Load("testdata/speedtest.json")
+//	client := &speedtest.Client{Transport: cassette.NewReplayer(c)}
+package cassette
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Body holds the response body, base64 encoded if Base64 is set because
+	// it isn't valid UTF-8.
+	Body   string `json:"body"`
+	Base64 bool   `json:"base64,omitempty"`
+}
+
+func (i Interaction) body() ([]byte, error) {
+	if i.Base64 {
+		return base64.StdEncoding.DecodeString(i.Body)
+	}
+	return []byte(i.Body), nil
+}
+
+// Cassette is a sequence of interactions in the order they were recorded.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Load reads a cassette saved with Save.
+func Load(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("cassette: could not parse %q: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path as JSON.
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: could not encode: %w", err)
+	}
+	return ioutil.WriteFile(path, b, 0o644)
+}
+
+// Discovery reports whether a request is part of speedtest.net or fast.com
+// discovery: the configuration, server lists, fast.com HTML and scripts and
+// the manifest. Speed and latency probes go to other hosts.
+func Discovery(req *http.Request) bool {
+	host := req.URL.Hostname()
+	return host == "speedtest.net" || strings.HasSuffix(host, ".speedtest.net") ||
+		host == "fast.com" || strings.HasSuffix(host, ".fast.com")
+}
+
+// Recorder is a RoundTripper recording the exchanges matching Match.
+type Recorder struct {
+	// Transport makes the actual requests. Nil means http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Match selects the requests to record. Nil means Discovery. Other
+	// requests are made but not recorded.
+	Match func(*http.Request) bool
+
+	mu sync.Mutex
+	c  Cassette
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	match := r.Match
+	if match == nil {
+		match = Discovery
+	}
+
+	res, err := t.RoundTrip(req)
+	if err != nil || !match(req) {
+		return res, err
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+
+	i := Interaction{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: res.StatusCode,
+		Header: res.Header.Clone(),
+	}
+	if utf8.Valid(b) {
+		i.Body = string(b)
+	} else {
+		i.Body, i.Base64 = base64.StdEncoding.EncodeToString(b), true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.Interactions = append(r.c.Interactions, i)
+	return res, nil
+}
+
+// Cassette returns a copy of what has been recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.c.Interactions...)}
+}
+
+// Replayer is a RoundTripper answering requests from a cassette.
+//
+// Requests are matched by method and URL. If no URL matches exactly, the query
+// is ignored, which lets a replayed fast.com manifest request carry a
+// different token. Requests recorded several times are answered in recorded
+// order, repeating the last answer once they run out.
+type Replayer struct {
+	// Fallback answers requests the cassette has no answer for. Nil means
+	// such requests fail, which keeps tests hermetic.
+	Fallback http.RoundTripper
+
+	c    *Cassette
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// NewReplayer returns a Replayer answering from c.
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{c: c, next: make(map[string]int)}
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	i, ok := r.find(req)
+	if !ok {
+		if r.Fallback != nil {
+			return r.Fallback.RoundTrip(req)
+		}
+		return nil, fmt.Errorf("cassette: no recorded response for %s %s", req.Method, req.URL)
+	}
+
+	b, err := i.body()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: corrupt body for %s %s: %w", req.Method, req.URL, err)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(string(b))),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Replayer) find(req *http.Request) (Interaction, bool) {
+	exact := req.Method + " " + req.URL.String()
+	u := *req.URL
+	u.RawQuery = ""
+	loose := req.Method + " " + u.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range []string{exact, loose} {
+		var found []int
+		for j, i := range r.c.Interactions {
+			if key == i.Method+" "+r.urlFor(i, key == loose) {
+				found = append(found, j)
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		n := r.next[key]
+		if n >= len(found) {
+			n = len(found) - 1
+		}
+		r.next[key] = n + 1
+		return r.c.Interactions[found[n]], true
+	}
+	return Interaction{}, false
+}
+
+// Returns the recorded URL, without its query if loose.
+func (r *Replayer) urlFor(i Interaction, loose bool) string {
+	if !loose {
+		return i.URL
+	}
+	if q := strings.IndexByte(i.URL, '?'); q >= 0 {
+		return i.URL[:q]
+	}
+	return i.URL
+}
@@ -0,0 +1,141 @@
+package cassette
+
+import (
+	"context"
+	"framey/assignment/internal/testutil"
+	"framey/assignment/pkg/fast"
+	"framey/assignment/pkg/speedtest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// Stands in for speedtest.net and fast.com.
+func newFakeUpstream(t *testing.T) http.RoundTripper {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/speedtest-config.php", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<settings><client ip="192.0.2.1" isp="Test ISP" lat="52.37" lon="4.89"/></settings>`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><script src="/app-c0ffee.js"></script></body></html>`))
+	})
+	mux.HandleFunc("/app-c0ffee.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`(function(){var a={token:"GoodToken",urlCount:5}})();`))
+	})
+	mux.HandleFunc("/netflix/speedtest/v2", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"client": {"asn": "64496", "isp": "Test ISP", "ip": "192.0.2.1",
+				"location": {"city": "Amsterdam", "country": "NL"}},
+			"targets": [{"name": "target", "url": "https://target.example/speedtest",
+				"location": {"city": "Amsterdam", "country": "NL"}}]
+		}`))
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{0xff, 0xfe, 0x00})
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return testutil.RedirectTransport(ts.URL)
+}
+
+// Records a run against the fake upstream and returns the replayed cassette.
+func recordAndLoad(t *testing.T, run func(http.RoundTripper)) *Cassette {
+	rec := &Recorder{Transport: newFakeUpstream(t)}
+	run(rec)
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := rec.Cassette().Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestReplayer_Speedtest(t *testing.T) {
+	var recorded speedtest.Config
+	c := recordAndLoad(t, func(rt http.RoundTripper) {
+		var err error
+		recorded, err = (&speedtest.Client{Transport: rt}).Config(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	client := &speedtest.Client{Transport: NewReplayer(c)}
+	replayed, err := client.Config(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if replayed != recorded {
+		t.Errorf("got: %+v, expected: %+v", replayed, recorded)
+	}
+}
+
+func TestReplayer_Fast(t *testing.T) {
+	c := recordAndLoad(t, func(rt http.RoundTripper) {
+		if _, err := (&fast.Client{Transport: rt}).GetManifest(context.Background(), 1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	if len(c.Interactions) != 3 {
+		t.Errorf("Expected the page, the script and the manifest, got %+v", c.Interactions)
+	}
+
+	// A stored token differs from the recorded one but is still answered.
+	client := &fast.Client{Transport: NewReplayer(c)}
+	m, err := client.GetManifestWithStore(context.Background(), 1, fast.StaticToken("OtherToken"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ts := m.Targets(); len(ts) != 1 || ts[0].Name != "target" {
+		t.Errorf("got: %+v", ts)
+	}
+}
+
+func TestReplayer_Unknown(t *testing.T) {
+	client := &http.Client{Transport: NewReplayer(&Cassette{})}
+	if _, err := client.Get("https://www.speedtest.net/speedtest-config.php"); err == nil {
+		t.Errorf("Expected an error for a request missing from the cassette")
+	}
+}
+
+func TestRecorder_Binary(t *testing.T) {
+	c := recordAndLoad(t, func(rt http.RoundTripper) {
+		res, err := (&http.Client{Transport: rt}).Get("https://fast.com/binary")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res.Body.Close()
+	})
+	if len(c.Interactions) != 1 || !c.Interactions[0].Base64 {
+		t.Fatalf("got: %+v", c.Interactions)
+	}
+
+	res, err := (&http.Client{Transport: NewReplayer(c)}).Get("https://fast.com/binary")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	if string(b) != "\xff\xfe\x00" {
+		t.Errorf("got: %q", b)
+	}
+}
+
+func TestRecorder_SkipsProbes(t *testing.T) {
+	c := recordAndLoad(t, func(rt http.RoundTripper) {
+		res, err := (&http.Client{Transport: rt}).Get("https://target.example/binary")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res.Body.Close()
+	})
+	if len(c.Interactions) != 0 {
+		t.Errorf("Recorded a probe: %+v", c.Interactions)
+	}
+}
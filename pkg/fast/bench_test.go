@@ -0,0 +1,52 @@
+package fast
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/internal/benchutil"
+	internal2 "framey/assignment/pkg/fast/internal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Stands in for a fast.com target, serving /range/0-<N> and accepting
+// uploads to it.
+func newBenchManifest(b *testing.B, c *benchutil.Counter) *Manifest {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var size int
+		if _, err := fmt.Sscanf(r.URL.Path, "/speedtest/range/0-%d", &size); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			c.Receive(r)
+			return
+		}
+		c.Send(w, size)
+	}))
+	b.Cleanup(ts.Close)
+	return &Manifest{m: &internal2.Manifest{
+		Targets: []internal2.ManifestTarget{{Name: "bench", URL: ts.URL + "/speedtest"}},
+	}}
+}
+
+func BenchmarkManifest_ProbeDownloadSpeed(b *testing.B) {
+	var c benchutil.Counter
+	m := newBenchManifest(b, &c)
+	client := &Client{}
+	benchutil.Run(b, &c, func() error {
+		_, err := m.ProbeDownloadSpeed(context.Background(), client, benchutil.Stream())
+		return err
+	})
+}
+
+func BenchmarkManifest_ProbeUploadSpeed(b *testing.B) {
+	var c benchutil.Counter
+	m := newBenchManifest(b, &c)
+	client := &Client{}
+	benchutil.Run(b, &c, func() error {
+		_, err := m.ProbeUploadSpeed(context.Background(), client, benchutil.Stream())
+		return err
+	})
+}
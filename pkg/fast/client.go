@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"framey/assignment/pkg/timing"
+	"framey/assignment/pkg/transport"
 	"io"
 	"net/http"
 	"net/url"
@@ -14,7 +16,16 @@ type Client http.Client
 
 type response http.Response
 
+// NewClient returns a client whose connections are made according to opts,
+// including the ones made to discover the manifest. The zero Client is also
+// usable and uses the system defaults.
+func NewClient(opts transport.Options) (*Client, error) {
+	c, err := opts.NewClient()
+	return (*Client)(c), err
+}
+
 func (c *Client) get(ctx context.Context, url string) (*response, error) {
+	ctx, finish := timing.Track(ctx)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("fast: could not create request to %q: %w", url, err)
@@ -23,10 +34,12 @@ func (c *Client) get(ctx context.Context, url string) (*response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("fast: could not make request to %q: %w", url, err)
 	}
+	finish(res)
 	return (*response)(res), nil
 }
 
 func (c *Client) post(ctx context.Context, url string, size int) (*response, error) {
+	ctx, finish := timing.Track(ctx)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, randomBlob(size))
 	if err != nil {
 		return nil, fmt.Errorf("fast: could not create request to %q: %w", url, err)
@@ -37,6 +50,7 @@ func (c *Client) post(ctx context.Context, url string, size int) (*response, err
 	if err != nil {
 		return nil, fmt.Errorf("fast: could not make request to %q: %w", url, err)
 	}
+	finish(res)
 	if res.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf(
 			"fast: could not perform upload: status %d %q",
@@ -25,6 +25,16 @@ func (m *Manifest) ProbeDownloadSpeed(
 	client *Client,
 	stream chan<- units.BytesPerSecond,
 ) (units.BytesPerSecond, error) {
+	t, err := m.ProbeDownload(ctx, client, stream)
+	return t.Speed, err
+}
+
+// ProbeDownload is like ProbeDownloadSpeed but also reports how much was transferred.
+func (m *Manifest) ProbeDownload(
+	ctx context.Context,
+	client *Client,
+	stream chan<- units.BytesPerSecond,
+) (Transfer, error) {
 	grp := prober.NewGroup(concurrentDownloadLimit)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -40,7 +50,17 @@ func (m *Manifest) ProbeDownloadSpeed(
 		}
 	}
 
-	return proberutil.SpeedCollect(grp, stream)
+	speed, b, err := proberutil.SpeedCollect(grp, stream)
+	return Transfer{Speed: speed, Bytes: int64(b)}, err
+}
+
+// Transfer is the outcome of a speed probe.
+type Transfer struct {
+	// Speed is the average speed over the probe.
+	Speed units.BytesPerSecond
+
+	// Bytes is how much the probe requests transferred.
+	Bytes int64
 }
 
 func (c *Client) downloadFile(
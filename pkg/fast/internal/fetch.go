@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/pkg/retry"
+	"io/ioutil"
+	"net/http"
+)
+
+// Fetches the body of u, retrying transient failures according to the policy
+// carried by ctx. what describes the document in error messages.
+func fetch(ctx context.Context, c *http.Client, u string, what string) (string, error) {
+	var body string
+	err := retry.FromContext(ctx).Do(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return fmt.Errorf("fast: could not create %s request: %w", what, err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			return fmt.Errorf("fast: could not get %s: %w", what, err)
+		}
+		defer res.Body.Close()
+		if err := retry.CheckStatus(res); err != nil {
+			return fmt.Errorf("fast: could not get %s: %w", what, err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("fast: could not read %s: %w", what, err)
+		}
+		body = string(b)
+		return nil
+	})
+	return body, err
+}
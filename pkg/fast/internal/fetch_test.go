@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"context"
+	"framey/assignment/pkg/retry"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetch_Retries(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	p := retry.DefaultPolicy
+	p.BaseDelay = time.Millisecond
+	ctx := retry.WithPolicy(context.Background(), p)
+
+	body, err := fetch(ctx, ts.Client(), ts.URL, "test document")
+	if err != nil || body != "ok" {
+		t.Errorf("got %q, %v", body, err)
+	}
+}
+
+func TestFetch_NoRetryOnForbidden(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	if _, err := fetch(context.Background(), ts.Client(), ts.URL, "test document"); err == nil {
+		t.Error("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests", calls)
+	}
+}
@@ -3,8 +3,9 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"framey/assignment/pkg/retry"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -33,8 +34,8 @@ type ManifestLocation struct {
 	Country string `json:"country"`
 }
 
-func GetManifest(ctx context.Context, token string, urls int) (*Manifest, error) {
-	ms, err := getManifestString(ctx, makeManifestURL(token, urls))
+func GetManifest(ctx context.Context, c *http.Client, token string, urls int) (*Manifest, error) {
+	ms, err := getManifestString(ctx, c, makeManifestURL(token, urls))
 	if err != nil {
 		return nil, err
 	}
@@ -50,21 +51,8 @@ func unmarshalManifest(s string) (*Manifest, error) {
 	return &m, nil
 }
 
-func getManifestString(ctx context.Context, u string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not create manifest request: %w", err)
-	}
-	res, err := c.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not get manifest: %w", err)
-	}
-	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not read manifest: %w", err)
-	}
-	return string(b), nil
+func getManifestString(ctx context.Context, c *http.Client, u string) (string, error) {
+	return fetch(ctx, c, u, "manifest")
 }
 
 func makeManifestURL(token string, urls int) string {
@@ -81,3 +69,13 @@ func makeManifestURL(token string, urls int) string {
 	u.RawQuery = q.Encode()
 	return u.String()
 }
+
+// TokenRejected reports whether err means the manifest API didn't accept the
+// token, as opposed to some transient or network failure.
+func TokenRejected(err error) bool {
+	var se *retry.StatusError
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.Code == http.StatusUnauthorized || se.Code == http.StatusForbidden
+}
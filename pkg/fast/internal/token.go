@@ -3,89 +3,119 @@ package internal
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 )
 
-func GetToken(ctx context.Context) (string, error) {
-	html, err := getHTML(ctx)
+// GetToken scrapes the API token from fast.com. The scripts referenced by the
+// HTML are tried in order: first the ones matching jsREs, then every other
+// script, until one of them contains something matching tokenREs.
+func GetToken(ctx context.Context, c *http.Client) (string, error) {
+	html, err := getHTML(ctx, c)
 	if err != nil {
 		return "", err
 	}
-	jsPath := extractJSPath(html)
-	if jsPath == "" {
-		return "", fmt.Errorf("fast: could not extract fast.com JS URL from the HTML")
-	}
-	js, err := getJS(ctx, jsPath)
-	if err != nil {
-		return "", err
+
+	scripts := scriptPaths(html)
+	if len(scripts) == 0 {
+		return "", &ExtractionError{Tried: []string{"fast.com HTML: no script references found"}}
 	}
-	tok := extractToken(js)
-	if tok == "" {
-		return "", fmt.Errorf("fast: could not extract fast.com token from JS")
+
+	var tried []string
+	for _, jsPath := range scripts {
+		js, err := getJS(ctx, c, jsPath)
+		if err != nil {
+			tried = append(tried, fmt.Sprintf("%s: %v", jsPath, err))
+			continue
+		}
+		if tok := extractToken(js); tok != "" {
+			return tok, nil
+		}
+		tried = append(tried, fmt.Sprintf(
+			"%s: no match for any of %d token patterns", jsPath, len(tokenREs)))
 	}
-	return tok, nil
+	return "", &ExtractionError{Tried: tried}
 }
 
-var c = &http.Client{}
+// ExtractionError lists every attempt made at extracting the token.
+type ExtractionError struct {
+	Tried []string
+}
 
-func getHTML(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://fast.com", nil)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not get fast.com HTML: %w", err)
-	}
-	res, err := c.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not get fast.com HTML: %w", err)
-	}
-	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not read fast.com HTML: %w", err)
-	}
-	return string(b), nil
+func (e *ExtractionError) Error() string {
+	return "fast: could not extract fast.com token, tried:\n\t" + strings.Join(e.Tried, "\n\t")
 }
 
-var jsRE = regexp.MustCompile("<script.*\"(/app-[[:xdigit:]]+\\.js)\"")
+func getHTML(ctx context.Context, c *http.Client) (string, error) {
+	return fetch(ctx, c, "https://fast.com", "fast.com HTML")
+}
+
+// Patterns for the script holding the token, most specific first.
+var jsREs = []*regexp.Regexp{
+	regexp.MustCompile("<script.*\"(/app-[[:xdigit:]]+\\.js)\""),
+	regexp.MustCompile(`<script[^>]+src=["']([^"']*/app[-.][^"']*\.js)["']`),
+}
+
+// Matches any script, for when none of jsREs do.
+var scriptRE = regexp.MustCompile(`<script[^>]+src=["']([^"']+\.js)["']`)
 
 func extractJSPath(html string) string {
-	m := jsRE.FindStringSubmatch(html)
-	if m == nil {
-		return ""
+	for _, re := range jsREs {
+		if m := re.FindStringSubmatch(html); m != nil {
+			return m[1]
+		}
 	}
-	return m[1]
+	return ""
 }
 
-func getJS(ctx context.Context, jsPath string) (string, error) {
-	u, err := url.Parse("https://fast.com")
-	if err != nil {
-		panic(err)
+// Returns the paths of the scripts referenced by html, the likeliest token
+// holders first and without duplicates.
+func scriptPaths(html string) []string {
+	var (
+		paths []string
+		seen  = make(map[string]bool)
+	)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
 	}
-	u.Path = jsPath
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return "", fmt.Errorf("fast: could not get fast.com JS: %w", err)
+
+	if p := extractJSPath(html); p != "" {
+		add(p)
+	}
+	for _, m := range scriptRE.FindAllStringSubmatch(html, -1) {
+		add(m[1])
 	}
-	res, err := c.Do(req)
+	return paths
+}
+
+func getJS(ctx context.Context, c *http.Client, jsPath string) (string, error) {
+	base, err := url.Parse("https://fast.com")
 	if err != nil {
-		return "", fmt.Errorf("fast: could not get fast.com JS: %w", err)
+		panic(err)
 	}
-	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
+	ref, err := url.Parse(jsPath)
 	if err != nil {
-		return "", fmt.Errorf("fast: could not read fast.com JS: %w", err)
+		return "", fmt.Errorf("fast: invalid script reference %q: %w", jsPath, err)
 	}
-	return string(b), nil
+	return fetch(ctx, c, base.ResolveReference(ref).String(), "fast.com JS")
 }
 
-var tokenRE = regexp.MustCompile("token:[\"']([[:alpha:]]+)['\"]")
+// Patterns for the token inside a script, most specific first.
+var tokenREs = []*regexp.Regexp{
+	regexp.MustCompile("token:[\"']([[:alpha:]]+)['\"]"),
+	regexp.MustCompile(`["']?token["']?\s*[:=]\s*["']([[:alnum:]]{16,})["']`),
+}
 
 func extractToken(js string) string {
-	m := tokenRE.FindStringSubmatch(js)
-	if m == nil {
-		return ""
+	for _, re := range tokenREs {
+		if m := re.FindStringSubmatch(js); m != nil {
+			return m[1]
+		}
 	}
-	return m[1]
+	return ""
 }
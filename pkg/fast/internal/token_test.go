@@ -1,6 +1,14 @@
 package internal
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"framey/assignment/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestExtractJSPath(t *testing.T) {
 	fakeHTML := `
@@ -29,3 +37,73 @@ const params={asdf:'FFFFF',token:'FooBarBaz'};
 		t.Errorf("got: %v", token)
 	}
 }
+
+func TestExtractJSPath_Fallback(t *testing.T) {
+	fakeHTML := `<script type="text/javascript" src='/assets/app.8a7b6c.js'></script>`
+	if jsPath := extractJSPath(fakeHTML); jsPath != "/assets/app.8a7b6c.js" {
+		t.Errorf("got: %v", jsPath)
+	}
+}
+
+func TestScriptPaths(t *testing.T) {
+	fakeHTML := `
+<script src="/vendor.js"></script>
+<script src="/app-888deadbeef888.js"></script>
+<script src="https://cdn.example/lib.js"></script>
+`
+	expected := []string{"/app-888deadbeef888.js", "/vendor.js", "https://cdn.example/lib.js"}
+	paths := scriptPaths(fakeHTML)
+	if len(paths) != len(expected) {
+		t.Fatalf("got: %v", paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("got: %v", paths)
+		}
+	}
+}
+
+func TestExtractToken_Fallback(t *testing.T) {
+	fakeJS := `var cfg = {"token": "YXNkZmFzZGxmbnNkYWZoYXNkZmhrYWxm", "urlCount": 5};`
+	if token := extractToken(fakeJS); token != "YXNkZmFzZGxmbnNkYWZoYXNkZmhrYWxm" {
+		t.Errorf("got: %v", token)
+	}
+}
+
+func TestGetToken_ScansAllScripts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<script src="/app-c0ffee.js"></script><script src="/other.js"></script>`))
+	})
+	mux.HandleFunc("/app-c0ffee.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`console.log("no token here")`))
+	})
+	mux.HandleFunc("/other.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`x={token:"FooBarBaz"}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tok, err := GetToken(context.Background(), &http.Client{Transport: testutil.RedirectTransport(ts.URL)})
+	if err != nil || tok != "FooBarBaz" {
+		t.Errorf("got %q, %v", tok, err)
+	}
+}
+
+func TestGetToken_DiagnosticError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<script src="/app-c0ffee.js"></script>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	_, err := GetToken(context.Background(), &http.Client{Transport: testutil.RedirectTransport(ts.URL)})
+	var ee *ExtractionError
+	if !errors.As(err, &ee) {
+		t.Fatalf("got: %v", err)
+	}
+	if len(ee.Tried) != 1 || !strings.Contains(ee.Tried[0], "/app-c0ffee.js") {
+		t.Errorf("Unexpected attempts: %q", ee.Tried)
+	}
+}
@@ -0,0 +1,130 @@
+package fast
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/pkg/retry"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultLatencySamples = 4
+
+// SortTargetsByAverageLatency probes every target concurrently and stable sorts
+// them based on their average latencies, like fast.com does before measuring
+// speed.
+//
+// Returns the average latencies keyed by each target's URL and an error if all
+// probes were unsuccessful. An error for a particular target gets treated as
+// if the latency was higher than any successful probe.
+func (m *Manifest) SortTargetsByAverageLatency(
+	ctx context.Context,
+	client *Client,
+	samples int,
+) (map[string]time.Duration, error) {
+	if samples <= 0 {
+		return nil, fmt.Errorf("fast: taking %v latency samples makes no sense", samples)
+	}
+
+	if len(m.m.Targets) == 0 {
+		return nil, fmt.Errorf("fast: manifest has no targets")
+	}
+
+	type result struct {
+		url string
+		d   time.Duration
+		err error
+	}
+	targets := m.m.Targets
+	c := make(chan result, len(targets))
+	var g sync.WaitGroup
+	for i := range targets {
+		u := targets[i].URL
+		g.Add(1)
+		go func() {
+			defer g.Done()
+			d, err := client.averageLatency(ctx, u, samples)
+			c <- result{u, d, err}
+		}()
+	}
+	g.Wait()
+	close(c)
+
+	var (
+		lat     = make(map[string]time.Duration, len(targets))
+		lastErr error
+		anyGood bool
+		dMax    = maxLatencyFor(ctx)
+	)
+	for r := range c {
+		if r.err != nil {
+			lastErr = r.err
+			r.d = dMax
+		} else {
+			anyGood = true
+		}
+		lat[r.url] = r.d
+	}
+	if !anyGood {
+		return nil, lastErr
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return lat[targets[i].URL] < lat[targets[j].URL]
+	})
+	return lat, nil
+}
+
+// KeepTargets drops all but the first n targets, e.g. after sorting them by
+// latency to skip the slowest ones. At least one target is always kept.
+func (m *Manifest) KeepTargets(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n < len(m.m.Targets) {
+		m.m.Targets = m.m.Targets[:n]
+	}
+}
+
+func maxLatencyFor(ctx context.Context) time.Duration {
+	if t, ok := ctx.Deadline(); ok {
+		return time.Until(t)
+	}
+	// Some silly-high sentinel value larger than any sensible result.
+	return 24 * time.Hour
+}
+
+// Takes samples of a target's latency and returns the average. Serialized and
+// fails fast, as a target that fails a tiny request is no good for a test.
+func (c *Client) averageLatency(ctx context.Context, url string, samples int) (time.Duration, error) {
+	var total time.Duration
+	for i := 0; i < samples; i++ {
+		d, err := c.latency(ctx, url)
+		if err != nil {
+			return 0, err
+		}
+		total += d
+	}
+	return total / time.Duration(samples), nil
+}
+
+// Measures the time it takes to fetch a single byte from a target.
+func (c *Client) latency(ctx context.Context, url string) (time.Duration, error) {
+	start := time.Now()
+	res, err := c.get(ctx, putSizeIntoURL(url, 0))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if err := retry.CheckStatus((*http.Response)(res)); err != nil {
+		return 0, fmt.Errorf("fast: latency probe failed: %w", err)
+	}
+	if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+		return 0, fmt.Errorf("fast: latency probe failed: %w", err)
+	}
+	return time.Since(start), nil
+}
@@ -0,0 +1,61 @@
+package fast
+
+import (
+	"context"
+	internal2 "framey/assignment/pkg/fast/internal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLatencyTestTarget(t *testing.T, latency time.Duration) internal2.ManifestTarget {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/range/0-0") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		time.Sleep(latency)
+		w.Write([]byte{0})
+	}))
+	t.Cleanup(ts.Close)
+	return internal2.ManifestTarget{Name: latency.String(), URL: ts.URL + "/speedtest"}
+}
+
+func TestManifest_SortTargetsByAverageLatency(t *testing.T) {
+	const timeScale = 10 * time.Millisecond
+
+	slow := newLatencyTestTarget(t, 3*timeScale)
+	fast := newLatencyTestTarget(t, timeScale)
+	broken := internal2.ManifestTarget{Name: "broken", URL: "http://127.0.0.1:1/speedtest"}
+	m := &Manifest{m: &internal2.Manifest{
+		Targets: []internal2.ManifestTarget{broken, slow, fast},
+	}}
+
+	lat, err := m.SortTargetsByAverageLatency(context.Background(), &Client{}, DefaultLatencySamples)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ts := m.Targets()
+	if ts[0].Name != fast.Name || ts[1].Name != slow.Name || ts[2].Name != broken.Name {
+		t.Errorf("Unexpected order: %+v", ts)
+	}
+	if lat[fast.URL] < timeScale || lat[fast.URL] >= lat[slow.URL] {
+		t.Errorf("Unexpected latencies: %v", lat)
+	}
+
+	m.KeepTargets(1)
+	if ts := m.Targets(); len(ts) != 1 || ts[0].Name != fast.Name {
+		t.Errorf("Unexpected targets: %+v", ts)
+	}
+}
+
+func TestManifest_SortTargetsByAverageLatency_AllFail(t *testing.T) {
+	m := &Manifest{m: &internal2.Manifest{
+		Targets: []internal2.ManifestTarget{{URL: "http://127.0.0.1:1/speedtest"}},
+	}}
+	if _, err := m.SortTargetsByAverageLatency(context.Background(), &Client{}, 1); err == nil {
+		t.Fail()
+	}
+}
@@ -2,21 +2,144 @@ package fast
 
 import (
 	"context"
+	"fmt"
 	internal2 "framey/assignment/pkg/fast/internal"
+	"net/http"
 )
 
+// Manifest is a set of fast.com test targets along with what's needed to ask
+// for a new set. It must not be refreshed while probing.
 type Manifest struct {
 	m *internal2.Manifest
+
+	client *Client
+	store  TokenStore
+	token  string
+	urls   int
 }
 
+// GetManifest discovers the given number of fast.com test URLs using the
+// default client.
 func GetManifest(ctx context.Context, urls int) (*Manifest, error) {
-	tok, err := internal2.GetToken(ctx)
-	if err != nil {
+	return new(Client).GetManifest(ctx, urls)
+}
+
+// GetManifest discovers the given number of fast.com test URLs, making every
+// request through c.
+func (c *Client) GetManifest(ctx context.Context, urls int) (*Manifest, error) {
+	return c.GetManifestWithStore(ctx, urls, nil)
+}
+
+// GetManifestWithStore is like GetManifest but reuses the API token kept in
+// store, if any, instead of scraping one from fast.com. Newly scraped tokens
+// are saved back into the store.
+func (c *Client) GetManifestWithStore(
+	ctx context.Context,
+	urls int,
+	store TokenStore,
+) (*Manifest, error) {
+	m := &Manifest{client: c, store: store, urls: urls}
+	if store != nil {
+		// A broken store is no worse than an empty one.
+		m.token, _ = store.LoadToken()
+	}
+	if err := m.Refresh(ctx); err != nil {
 		return nil, err
 	}
-	mi, err := internal2.GetManifest(ctx, tok, urls)
+	return m, nil
+}
+
+// Refresh replaces the targets with a new set from the fast.com API. The
+// current token is reused unless the API rejects it, in which case a new one
+// is scraped from fast.com and the request is repeated.
+func (m *Manifest) Refresh(ctx context.Context) error {
+	hc := (*http.Client)(m.client)
+	var rejected error
+	if m.token != "" {
+		mi, err := internal2.GetManifest(ctx, hc, m.token, m.urls)
+		if err == nil {
+			m.m = mi
+			return nil
+		}
+		if !internal2.TokenRejected(err) {
+			return err
+		}
+		rejected = err
+	}
+
+	tok, err := internal2.GetToken(ctx, hc)
 	if err != nil {
-		return nil, err
+		if rejected != nil {
+			return fmt.Errorf("%w\n\tand the known token was rejected: %v", err, rejected)
+		}
+		return err
+	}
+	m.token = tok
+	if m.store != nil {
+		// The token still works for this run even if it can't be saved.
+		_ = m.store.StoreToken(tok)
+	}
+
+	mi, err := internal2.GetManifest(ctx, hc, tok, m.urls)
+	if err != nil {
+		return err
+	}
+	m.m = mi
+	return nil
+}
+
+// Location is a place as reported by fast.com.
+type Location struct {
+	City    string
+	Country string
+}
+
+func (l Location) String() string {
+	switch {
+	case l.City == "":
+		return l.Country
+	case l.Country == "":
+		return l.City
+	default:
+		return l.City + ", " + l.Country
+	}
+}
+
+// ClientInfo describes the client as seen by fast.com.
+type ClientInfo struct {
+	ASN      string
+	ISP      string
+	IP       string
+	Location Location
+}
+
+// Target is a server that the probes transfer data from and to.
+type Target struct {
+	Name     string
+	URL      string
+	Location Location
+}
+
+// Client returns what fast.com knows about the client.
+func (m *Manifest) Client() ClientInfo {
+	c := m.m.Client
+	return ClientInfo{
+		ASN:      c.ASN,
+		ISP:      c.ISP,
+		IP:       c.IP,
+		Location: Location(c.Location),
+	}
+}
+
+// Targets returns a copy of the servers the probes use.
+func (m *Manifest) Targets() []Target {
+	ts := make([]Target, len(m.m.Targets))
+	for i, t := range m.m.Targets {
+		ts[i] = Target{
+			Name:     t.Name,
+			URL:      t.URL,
+			Location: Location(t.Location),
+		}
 	}
-	return &Manifest{mi}, nil
+	return ts
 }
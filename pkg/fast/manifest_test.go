@@ -0,0 +1,140 @@
+package fast
+
+import (
+	"context"
+	"framey/assignment/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Stands in for fast.com and its API, only accepting the token it hands out.
+func newFakeFast(t *testing.T, token string) (*Client, *int32) {
+	var scrapes int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&scrapes, 1)
+		w.Write([]byte(`<html><body><script src="/app-c0ffee.js"></script></body></html>`))
+	})
+	mux.HandleFunc("/app-c0ffee.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`(function(){var a={token:"` + token + `",urlCount:5}})();`))
+	})
+	mux.HandleFunc("/netflix/speedtest/v2", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{
+			"client": {"asn": "64496", "isp": "Test ISP", "ip": "192.0.2.1",
+				"location": {"city": "Amsterdam", "country": "NL"}},
+			"targets": [{"name": "target", "url": "https://target.example/speedtest",
+				"location": {"city": "Amsterdam", "country": "NL"}}]
+		}`))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return &Client{Transport: testutil.RedirectTransport(ts.URL)}, &scrapes
+}
+
+type memTokenStore struct{ token string }
+
+func (s *memTokenStore) LoadToken() (string, error) { return s.token, nil }
+
+func (s *memTokenStore) StoreToken(token string) error {
+	s.token = token
+	return nil
+}
+
+func TestClient_GetManifestWithStore_Reuse(t *testing.T) {
+	client, scrapes := newFakeFast(t, "GoodToken")
+	store := &memTokenStore{token: "GoodToken"}
+
+	m, err := client.GetManifestWithStore(context.Background(), 1, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(scrapes); n != 0 {
+		t.Errorf("Scraped fast.com %d times despite a stored token", n)
+	}
+	if len(m.m.Targets) != 1 {
+		t.Errorf("got: %+v", m.m)
+	}
+}
+
+func TestClient_GetManifestWithStore_Rejected(t *testing.T) {
+	client, scrapes := newFakeFast(t, "GoodToken")
+	store := &memTokenStore{token: "StaleToken"}
+
+	if _, err := client.GetManifestWithStore(context.Background(), 1, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(scrapes); n != 1 {
+		t.Errorf("Expected a single scrape, got %d", n)
+	}
+	if store.token != "GoodToken" {
+		t.Errorf("Stored token is %q", store.token)
+	}
+}
+
+func TestManifest_Refresh(t *testing.T) {
+	client, scrapes := newFakeFast(t, "GoodToken")
+
+	m, err := client.GetManifest(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(scrapes); n != 1 {
+		t.Errorf("Expected the token to be reused on refresh, scraped %d times", n)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	s := FileTokenStore{Dir: t.TempDir()}
+	if tok, err := s.LoadToken(); tok != "" || err != nil {
+		t.Errorf("Expected an empty store, got %q, %v", tok, err)
+	}
+	if err := s.StoreToken("FooBarBaz"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tok, err := s.LoadToken(); tok != "FooBarBaz" || err != nil {
+		t.Errorf("got %q, %v", tok, err)
+	}
+}
+
+func TestClient_GetManifestWithStore_StaticTokenRejected(t *testing.T) {
+	client, scrapes := newFakeFast(t, "GoodToken")
+
+	m, err := client.GetManifestWithStore(context.Background(), 1, StaticToken("UserToken"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(scrapes); n != 1 || m.token != "GoodToken" {
+		t.Errorf("Expected to fall back to scraping, scraped %d times", n)
+	}
+}
+
+func TestManifest_Accessors(t *testing.T) {
+	client, _ := newFakeFast(t, "GoodToken")
+	m, err := client.GetManifest(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c := m.Client()
+	if c.ASN != "64496" || c.ISP != "Test ISP" || c.IP != "192.0.2.1" || c.Location.String() != "Amsterdam, NL" {
+		t.Errorf("Unexpected client: %+v", c)
+	}
+
+	ts := m.Targets()
+	if len(ts) != 1 || ts[0].Name != "target" || ts[0].Location.City != "Amsterdam" {
+		t.Fatalf("Unexpected targets: %+v", ts)
+	}
+	ts[0].URL = "changed"
+	if m.Targets()[0].URL == "changed" {
+		t.Error("Targets exposed the manifest's internals")
+	}
+}
@@ -0,0 +1,47 @@
+package fast
+
+import (
+	"framey/assignment/internal/diskcache"
+	"os"
+)
+
+// TokenStore persists the fast.com API token between runs, sparing the slow
+// and fragile scraping of fast.com.
+type TokenStore interface {
+	// LoadToken returns the stored token, or an empty string if there is none.
+	LoadToken() (string, error)
+	StoreToken(token string) error
+}
+
+// FileTokenStore keeps the token in a file inside Dir.
+type FileTokenStore struct {
+	Dir string
+}
+
+const tokenEntry = "fast-token"
+
+func (s FileTokenStore) LoadToken() (string, error) {
+	var tok string
+	_, err := diskcache.Dir(s.Dir).Load(tokenEntry, &tok)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return tok, err
+}
+
+func (s FileTokenStore) StoreToken(token string) error {
+	return diskcache.Dir(s.Dir).Store(tokenEntry, token)
+}
+
+// StaticToken is a token supplied by the user, e.g. through the FAST_TOKEN
+// environment variable. It is tried before scraping fast.com, and scraped
+// tokens are never saved over it.
+type StaticToken string
+
+func (t StaticToken) LoadToken() (string, error) {
+	return string(t), nil
+}
+
+func (StaticToken) StoreToken(string) error {
+	return nil
+}
@@ -24,6 +24,16 @@ func (m *Manifest) ProbeUploadSpeed(
 	client *Client,
 	stream chan<- units.BytesPerSecond,
 ) (units.BytesPerSecond, error) {
+	t, err := m.ProbeUpload(ctx, client, stream)
+	return t.Speed, err
+}
+
+// ProbeUpload is like ProbeUploadSpeed but also reports how much was transferred.
+func (m *Manifest) ProbeUpload(
+	ctx context.Context,
+	client *Client,
+	stream chan<- units.BytesPerSecond,
+) (Transfer, error) {
 	grp := prober.NewGroup(concurrentUploadLimit)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -40,7 +50,8 @@ func (m *Manifest) ProbeUploadSpeed(
 		}
 	}
 
-	return proberutil.SpeedCollect(grp, stream)
+	speed, b, err := proberutil.SpeedCollect(grp, stream)
+	return Transfer{Speed: speed, Bytes: int64(b)}, err
 }
 
 func (c *Client) uploadFile(
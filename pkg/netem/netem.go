@@ -0,0 +1,230 @@
+// Package netem emulates network links in-process, so that the probes can be
+// exercised against local httptest servers under known conditions.
+//
+// A Link wraps connections on the client side, through Transport or Dialer,
+// or on the server side, through Listener:
+//
+//	ts := httptest.NewServer(handler)
+//	client := &speedtest.Client{Transport: netem.DSL.Transport()}
+//
+// Bandwidth is enforced by pacing reads and writes, latency is added whenever
+// the direction of the traffic turns around, as in a request followed by its
+// response, and stalls pause the connection at random.
+package netem
+
+import (
+	"context"
+	"framey/assignment/internal/units"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Link describes the conditions of an emulated link, from the client's point
+// of view. Zero values mean no limit or no delay.
+type Link struct {
+	// Down and Up are the bandwidth towards and from the client.
+	Down, Up units.BitsPerSecond
+
+	// Latency is the one-way delay, varied at random by up to Jitter either
+	// way.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// StallRate is the probability of any read or write stalling the
+	// connection for StallTime.
+	StallRate float64
+	StallTime time.Duration
+}
+
+// Typical links.
+var (
+	DSL = Link{
+		Down:    10 * units.Mbps,
+		Up:      1 * units.Mbps,
+		Latency: 15 * time.Millisecond,
+		Jitter:  3 * time.Millisecond,
+	}
+	LTE = Link{
+		Down:      30 * units.Mbps,
+		Up:        10 * units.Mbps,
+		Latency:   25 * time.Millisecond,
+		Jitter:    10 * time.Millisecond,
+		StallRate: 0.001,
+		StallTime: 200 * time.Millisecond,
+	}
+	Fibre = Link{
+		Down:    1 * units.Gbps,
+		Up:      1 * units.Gbps,
+		Latency: 2 * time.Millisecond,
+		Jitter:  500 * time.Microsecond,
+	}
+)
+
+// DialContext is the signature of net.Dialer.DialContext.
+type DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Conn wraps a client side connection.
+func (l Link) Conn(c net.Conn) net.Conn {
+	return newConn(c, l, l.Down, l.Up)
+}
+
+// Dialer wraps dial, net.Dialer's if nil, so that the connections it makes go
+// through the link. Dialing takes a round trip, like a TCP handshake.
+func (l Link) Dialer(dial DialContext) DialContext {
+	if dial == nil {
+		dial = new(net.Dialer).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(l.delay() + l.delay()):
+		case <-ctx.Done():
+			c.Close()
+			return nil, ctx.Err()
+		}
+		return l.Conn(c), nil
+	}
+}
+
+// Transport returns a copy of http.DefaultTransport whose connections go
+// through the link.
+func (l Link) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = l.Dialer(nil)
+	return t
+}
+
+// Listener wraps the server side of the link, e.g. the listener of an
+// unstarted httptest server.
+func (l Link) Listener(ln net.Listener) net.Listener {
+	return listener{ln, l}
+}
+
+type listener struct {
+	net.Listener
+	l Link
+}
+
+func (ln listener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	// The server reads what the client sends.
+	return newConn(c, ln.l, ln.l.Up, ln.l.Down), nil
+}
+
+// Returns the latency of a single trip.
+func (l Link) delay() time.Duration {
+	d := l.Latency
+	if l.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(2*l.Jitter)+1)) - l.Jitter
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func (l Link) stall() {
+	if l.StallRate > 0 && rand.Float64() < l.StallRate {
+		time.Sleep(l.StallTime)
+	}
+}
+
+// Reads and writes are split into chunks of at most this size so that pacing
+// stays smooth.
+const chunk = 16 << 10
+
+type conn struct {
+	net.Conn
+	l Link
+
+	read, write pacer
+
+	// Whether there was a write since a read last returned data, in which
+	// case the data read next waits for the trip both ways. It is checked
+	// once the read returns, since a read may be waiting before the write.
+	mu    sync.Mutex
+	wrote bool
+}
+
+func newConn(c net.Conn, l Link, read, write units.BitsPerSecond) *conn {
+	return &conn{
+		Conn:  c,
+		l:     l,
+		read:  pacer{rate: read.BytesPerSecond()},
+		write: pacer{rate: write.BytesPerSecond()},
+	}
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if len(b) > chunk {
+		b = b[:chunk]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.mu.Lock()
+		turned := c.wrote
+		c.wrote = false
+		c.mu.Unlock()
+		if turned {
+			time.Sleep(c.l.delay() + c.l.delay())
+		}
+	}
+	c.l.stall()
+	c.read.wait(n)
+	return n, err
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.wrote = true
+	c.mu.Unlock()
+
+	written := 0
+	for len(b) > 0 {
+		p := b
+		if len(p) > chunk {
+			p = p[:chunk]
+		}
+		c.l.stall()
+		c.write.wait(len(p))
+		n, err := c.Conn.Write(p)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// Spaces out transfers so that they don't exceed rate.
+type pacer struct {
+	rate units.BytesPerSecond
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (p *pacer) wait(n int) {
+	if p.rate <= 0 || n <= 0 {
+		return
+	}
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	p.next = p.next.Add(time.Duration(float64(n) / float64(p.rate) * float64(time.Second)))
+	until := p.next
+	p.mu.Unlock()
+	time.Sleep(time.Until(until))
+}
@@ -0,0 +1,113 @@
+package netem
+
+import (
+	"context"
+	"framey/assignment/internal/units"
+	"framey/assignment/pkg/speedtest"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPayloadServer(size int) *httptest.Server {
+	payload := strings.Repeat("x", size)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.Write([]byte(payload))
+	}))
+}
+
+func roundTrip(t *testing.T, client *http.Client, url string, body io.Reader) time.Duration {
+	start := time.Now()
+	res, err := client.Post(url, "application/octet-stream", body)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return time.Since(start)
+}
+
+func TestLink_Bandwidth(t *testing.T) {
+	const size = 256 << 10
+	ts := newPayloadServer(size)
+	defer ts.Close()
+
+	// 1 MB/s each way, so that either transfer takes a quarter of a second.
+	l := Link{Down: 8 * units.Mbps, Up: 8 * units.Mbps}
+	client := &http.Client{Transport: l.Transport()}
+
+	if d := roundTrip(t, client, ts.URL, nil); d < 200*time.Millisecond {
+		t.Errorf("Downloaded %d bytes in %v", size, d)
+	}
+	if d := roundTrip(t, client, ts.URL, strings.NewReader(strings.Repeat("x", size))); d < 200*time.Millisecond {
+		t.Errorf("Uploaded %d bytes in %v", size, d)
+	}
+}
+
+func TestLink_Listener(t *testing.T) {
+	const size = 256 << 10
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", size)))
+	}))
+	ts.Listener = Link{Down: 8 * units.Mbps}.Listener(ts.Listener)
+	ts.Start()
+	defer ts.Close()
+
+	if d := roundTrip(t, ts.Client(), ts.URL, nil); d < 200*time.Millisecond {
+		t.Errorf("Downloaded %d bytes in %v", size, d)
+	}
+}
+
+func TestLink_Latency(t *testing.T) {
+	const latency = 20 * time.Millisecond
+	ts := newPayloadServer(1)
+	defer ts.Close()
+
+	l := Link{Latency: latency, Jitter: latency / 4}
+	client := &http.Client{Transport: l.Transport()}
+
+	// The first request also pays for dialing.
+	if d := roundTrip(t, client, ts.URL, nil); d < 4*(latency-l.Jitter) {
+		t.Errorf("First round trip took %v", d)
+	}
+	for i := 1; i < 3; i++ {
+		if d := roundTrip(t, client, ts.URL, nil); d < 2*(latency-l.Jitter) {
+			t.Errorf("Round trip %d took %v", i, d)
+		}
+	}
+}
+
+func TestLink_SpeedtestLatency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test=test"))
+	}))
+	defer ts.Close()
+
+	client := &speedtest.Client{Transport: DSL.Transport()}
+	s := speedtest.Server{URL: ts.URL}
+	lat, err := s.AverageLatency(context.Background(), client, speedtest.DefaultLatencySamples)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if min := 2 * (DSL.Latency - DSL.Jitter); lat < min {
+		t.Errorf("Latency of %v under DSL, expected at least %v", lat, min)
+	}
+}
+
+func TestLink_Stalls(t *testing.T) {
+	ts := newPayloadServer(1)
+	defer ts.Close()
+
+	l := Link{StallRate: 1, StallTime: 50 * time.Millisecond}
+	client := &http.Client{Transport: l.Transport()}
+	if d := roundTrip(t, client, ts.URL, nil); d < l.StallTime {
+		t.Errorf("Request took %v despite stalling", d)
+	}
+}
@@ -0,0 +1,148 @@
+// Package retry retries the discovery requests of the speedtest.net and
+// fast.com clients with exponential backoff and jitter.
+//
+// Speed probes are never retried since a retried transfer would distort the
+// measurement.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Policy describes how failed requests are retried.
+type Policy struct {
+	// Attempts is the total number of attempts, including the first one.
+	// Values below 1 are treated as 1.
+	Attempts int
+
+	// BaseDelay is the delay before the first retry. It doubles for every
+	// retry after that, up to MaxDelay. The actual delay is drawn uniformly
+	// from [0, delay) to keep clients from retrying in lockstep.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// RetryableStatus lists the HTTP status codes worth retrying. Timeouts,
+	// refused and reset connections and truncated responses are always
+	// retried.
+	RetryableStatus []int
+}
+
+// DefaultPolicy is used when a context doesn't carry a policy.
+var DefaultPolicy = Policy{
+	Attempts:  3,
+	BaseDelay: 250 * time.Millisecond,
+	MaxDelay:  2 * time.Second,
+	RetryableStatus: []int{
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// Never makes a single attempt.
+var Never = Policy{Attempts: 1}
+
+type policyKey struct{}
+
+// WithPolicy returns a copy of ctx that makes discovery requests retry
+// according to p.
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+// FromContext returns the policy carried by ctx, or DefaultPolicy.
+func FromContext(ctx context.Context) Policy {
+	if p, ok := ctx.Value(policyKey{}).(Policy); ok {
+		return p
+	}
+	return DefaultPolicy
+}
+
+// StatusError reports a response with an unexpected HTTP status.
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%q returned HTTP status %d %s", e.URL, e.Code, http.StatusText(e.Code))
+}
+
+// CheckStatus returns a *StatusError unless res has a 200 OK status.
+func CheckStatus(res *http.Response) error {
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &StatusError{URL: res.Request.URL.String(), Code: res.StatusCode}
+}
+
+// Do calls fn until it succeeds, fails with an error that isn't worth
+// retrying, the attempts run out or ctx is done. It returns fn's last error.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt+1 >= p.Attempts || !p.Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		t := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// Retryable reports whether err is likely to be transient.
+func (p Policy) Retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		for _, c := range p.RetryableStatus {
+			if se.Code == c {
+				return true
+			}
+		}
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// Anything else, such as a rejected certificate, an unsupported scheme or
+	// a proxy refusing to connect, would only fail again.
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// Backoff returns the randomized delay before retrying the given (zero based)
+// attempt.
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 0; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
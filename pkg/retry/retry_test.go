@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var fastPolicy = Policy{
+	Attempts:        3,
+	BaseDelay:       time.Millisecond,
+	MaxDelay:        4 * time.Millisecond,
+	RetryableStatus: DefaultPolicy.RetryableStatus,
+}
+
+func TestPolicy_Do_RetriesTransientErrors(t *testing.T) {
+	var calls int
+	err := fastPolicy.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return &StatusError{URL: "http://test", Code: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+}
+
+func TestPolicy_Do_GivesUp(t *testing.T) {
+	var calls int
+	err := fastPolicy.Do(context.Background(), func(context.Context) error {
+		calls++
+		return &url.Error{Op: "Get", URL: "http://test", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}
+	})
+	if err == nil || calls != fastPolicy.Attempts {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+}
+
+func TestPolicy_Do_PermanentErrors(t *testing.T) {
+	for _, e := range []error{
+		&StatusError{URL: "http://test", Code: http.StatusForbidden},
+		errors.New("could not parse"),
+		&url.Error{Op: "Get", URL: "ftp://test", Err: errors.New("unsupported protocol scheme")},
+		&url.Error{Op: "Get", URL: "https://test", Err: x509.UnknownAuthorityError{}},
+		&url.Error{Op: "Get", URL: "https://test", Err: &net.OpError{Op: "proxyconnect", Err: errors.New("Forbidden")}},
+	} {
+		var calls int
+		err := fastPolicy.Do(context.Background(), func(context.Context) error {
+			calls++
+			return e
+		})
+		if err != e || calls != 1 {
+			t.Errorf("%v: got %v after %d calls", e, err, calls)
+		}
+	}
+}
+
+func TestPolicy_Retryable(t *testing.T) {
+	for _, e := range []error{
+		&url.Error{Op: "Get", URL: "http://test", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}},
+		&url.Error{Op: "Get", URL: "http://test", Err: &net.DNSError{Err: "timeout", IsTimeout: true}},
+		fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF),
+	} {
+		if !fastPolicy.Retryable(e) {
+			t.Errorf("%v: expected to be retryable", e)
+		}
+	}
+}
+
+func TestPolicy_Do_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := fastPolicy
+	p.BaseDelay = time.Hour
+	p.MaxDelay = time.Hour
+
+	var calls int
+	err := p.Do(ctx, func(context.Context) error {
+		calls++
+		cancel()
+		return &StatusError{URL: "http://test", Code: http.StatusBadGateway}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		if d := fastPolicy.Backoff(attempt); d < 0 || d >= fastPolicy.MaxDelay {
+			t.Errorf("attempt %d: backoff %v out of range", attempt, d)
+		}
+	}
+	if d := Never.Backoff(3); d != 0 {
+		t.Errorf("got: %v", d)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if p := FromContext(context.Background()); p.Attempts != DefaultPolicy.Attempts {
+		t.Errorf("got: %+v", p)
+	}
+	if p := FromContext(WithPolicy(context.Background(), Never)); p.Attempts != 1 {
+		t.Errorf("got: %+v", p)
+	}
+}
@@ -0,0 +1,50 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/internal/benchutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Stands in for a speedtest.net server, serving random<N>x<N>.jpg images of
+// about the real size and accepting uploads.
+func newBenchServer(b *testing.B, c *benchutil.Counter) Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			c.Receive(r)
+			w.Write([]byte("size=0"))
+			return
+		}
+		var size int
+		if _, err := fmt.Sscanf(r.URL.Path, "/random%dx", &size); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		c.Send(w, 2*size*size)
+	}))
+	b.Cleanup(ts.Close)
+	return Server{ID: 1, URL: ts.URL + "/upload.php"}
+}
+
+func BenchmarkServer_ProbeDownloadSpeed(b *testing.B) {
+	var c benchutil.Counter
+	s := newBenchServer(b, &c)
+	client := &Client{}
+	benchutil.Run(b, &c, func() error {
+		_, err := s.ProbeDownloadSpeed(context.Background(), client, benchutil.Stream())
+		return err
+	})
+}
+
+func BenchmarkServer_ProbeUploadSpeed(b *testing.B) {
+	var c benchutil.Counter
+	s := newBenchServer(b, &c)
+	client := &Client{}
+	benchutil.Run(b, &c, func() error {
+		_, err := s.ProbeUploadSpeed(context.Background(), client, benchutil.Stream())
+		return err
+	})
+}
@@ -0,0 +1,96 @@
+package speedtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"framey/assignment/internal/diskcache"
+	"time"
+)
+
+// Cache keeps the speedtest.net configuration and server list on disk so that
+// repeated runs don't have to download them every time.
+//
+// Entries younger than their TTL are used without touching the network. Older
+// entries are refreshed, but are still used if the refresh fails.
+type Cache struct {
+	// Dir is the directory holding the cache entries.
+	Dir string
+
+	// Scope tells apart entries fetched over different network paths, e.g.
+	// through different source addresses or proxies, since the configuration
+	// describes the client's public IP and ISP.
+	Scope string
+
+	ConfigTTL  time.Duration
+	ServersTTL time.Duration
+
+	// Refresh ignores fresh entries and always tries the network first.
+	Refresh bool
+}
+
+// CachedConfig is like Config but goes through the cache. A nil cache always
+// uses the network.
+func (c *Client) CachedConfig(ctx context.Context, cache *Cache) (Config, error) {
+	if cache == nil {
+		return c.Config(ctx)
+	}
+	var cfg Config
+	err := cache.load("config", cache.ConfigTTL, &cfg, func() error {
+		fresh, err := c.Config(ctx)
+		if err == nil {
+			cfg = fresh
+		}
+		return err
+	})
+	return cfg, err
+}
+
+// CachedServers is like LoadAllServers but goes through the cache. A nil cache
+// always uses the network.
+func (c *Client) CachedServers(ctx context.Context, cache *Cache) ([]Server, error) {
+	if cache == nil {
+		return c.LoadAllServers(ctx)
+	}
+	var servers []Server
+	err := cache.load("servers", cache.ServersTTL, &servers, func() error {
+		fresh, err := c.LoadAllServers(ctx)
+		if err == nil {
+			servers = fresh
+		}
+		return err
+	})
+	return servers, err
+}
+
+// Loads the named entry into v, calling fetch to refresh it if it's older than
+// ttl. fetch must only overwrite v when it succeeds.
+func (cc *Cache) load(name string, ttl time.Duration, v interface{}, fetch func() error) error {
+	d := diskcache.Dir(cc.Dir)
+	name = cc.entryName(name)
+
+	stored, cacheErr := d.Load(name, v)
+	if cacheErr == nil && !cc.Refresh && time.Since(stored) < ttl {
+		return nil
+	}
+
+	if err := fetch(); err != nil {
+		if cacheErr == nil {
+			// Stale is better than nothing.
+			return nil
+		}
+		return err
+	}
+
+	// Failing to cache shouldn't fail the test.
+	_ = d.Store(name, v)
+	return nil
+}
+
+func (cc *Cache) entryName(name string) string {
+	if cc.Scope == "" {
+		return name
+	}
+	h := sha256.Sum256([]byte(cc.Scope))
+	return name + "-" + hex.EncodeToString(h[:8])
+}
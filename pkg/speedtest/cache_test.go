@@ -0,0 +1,92 @@
+package speedtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_CachedServers(t *testing.T) {
+	var calls, broken int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&broken) != 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(testServerList))
+	}))
+	defer ts.Close()
+	withMirrors(t, ts.URL)
+
+	var (
+		client Client
+		ctx    = context.Background()
+		cache  = &Cache{Dir: t.TempDir(), ServersTTL: time.Hour}
+	)
+
+	load := func(name string) []Server {
+		servers, err := client.CachedServers(ctx, cache)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(servers) != 2 {
+			t.Errorf("%s: got %v", name, servers)
+		}
+		return servers
+	}
+
+	load("cold")
+	load("fresh")
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected the fresh entry to be used, made %d requests", n)
+	}
+
+	atomic.StoreInt32(&broken, 1)
+	cache.Refresh = true
+	load("stale")
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected a refresh attempt, made %d requests", n)
+	}
+
+	cache.Dir = t.TempDir()
+	if _, err := client.CachedServers(ctx, cache); err == nil {
+		t.Error("Expected an error with nothing cached")
+	}
+}
+
+func TestClient_CachedConfig_Scope(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<settings><client ip="192.0.2.1" lat="1.5" lon="2.5" isp="Test ISP" /></settings>`))
+	}))
+	defer ts.Close()
+
+	old := configURL
+	configURL = ts.URL
+	defer func() { configURL = old }()
+
+	var (
+		client Client
+		ctx    = context.Background()
+		dir    = t.TempDir()
+	)
+	cfg, err := client.CachedConfig(ctx, &Cache{Dir: dir, Scope: "eth0", ConfigTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.IP != "192.0.2.1" || cfg.ISP != "Test ISP" || cfg.Coordinates.Longitude != 2.5 {
+		t.Errorf("got: %+v", cfg)
+	}
+
+	// A different network path must not see the other one's configuration.
+	ts.Close()
+	if _, err := client.CachedConfig(ctx, &Cache{Dir: dir, Scope: "eth1", ConfigTTL: time.Hour}); err == nil {
+		t.Error("Expected a cache miss for a different scope")
+	}
+	if _, err := client.CachedConfig(ctx, &Cache{Dir: dir, Scope: "eth0", ConfigTTL: time.Hour}); err != nil {
+		t.Errorf("Expected a cache hit, got: %v", err)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"framey/assignment/pkg/retry"
+	"framey/assignment/pkg/timing"
+	"framey/assignment/pkg/transport"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -15,8 +18,17 @@ type Client http.Client
 
 type response http.Response
 
+// NewClient returns a client whose connections are made according to opts.
+// The zero Client is also usable and uses the system defaults.
+func NewClient(opts transport.Options) (*Client, error) {
+	c, err := opts.NewClient()
+	return (*Client)(c), err
+}
+
 func (c *Client) get(ctx context.Context, url string) (resp *response, err error) {
+	ctx, finish := timing.Track(ctx)
 	htResp, err := ctxhttp.Get(ctx, (*http.Client)(c), url)
+	finish(htResp)
 	return (*response)(htResp), err
 }
 
@@ -34,11 +46,29 @@ func (c *Client) post(ctx context.Context, url string, bodyType string, body io.
 
 	req.Header.Set("Content-Type", bodyType)
 	req.ContentLength = int64(buf.Len())
+	ctx, finish := timing.Track(ctx)
 	htResp, err := ctxhttp.Do(ctx, (*http.Client)(c), req)
+	finish(htResp)
 
 	return (*response)(htResp), err
 }
 
+// Fetches and decodes an XML document, retrying transient failures according
+// to the policy carried by ctx.
+func (c *Client) getXML(ctx context.Context, url string, out interface{}) error {
+	return retry.FromContext(ctx).Do(ctx, func(ctx context.Context) error {
+		res, err := c.get(ctx, url)
+		if err != nil {
+			return err
+		}
+		if err := retry.CheckStatus((*http.Response)(res)); err != nil {
+			res.Body.Close()
+			return err
+		}
+		return res.ReadXML(out)
+	})
+}
+
 func (res *response) ReadContent() ([]byte, error) {
 	var content []byte
 	if c, err := ioutil.ReadAll(res.Body); err != nil {
@@ -2,7 +2,10 @@ package speedtest
 
 import (
 	"context"
+	"encoding/xml"
+	"fmt"
 	"framey/assignment/internal/geo"
+	"io/ioutil"
 )
 
 type Config struct {
@@ -13,31 +16,58 @@ type Config struct {
 	ISPDownloadAverage uint    `xml:"ispdlavg,attr"`
 	ISPUploadAverage   uint    `xml:"ispulavg,attr"`
 	Rating             float32 `xml:"rating,attr"`
+
+	// LocationSource tells where Coordinates came from.
+	LocationSource LocationSource `xml:"-"`
+}
+
+var configURL = "https://www.speedtest.net/speedtest-config.php"
+
+// The speedtest-config.php document.
+type configDocument struct {
+	Client struct {
+		Config
+		Latitude  float64 `xml:"lat,attr"`
+		Longitude float64 `xml:"lon,attr"`
+	} `xml:"client"`
 }
 
+func (d *configDocument) config() Config {
+	config := d.Client.Config
+	config.Locate(geo.Coordinates{
+		Latitude:  geo.Degrees(d.Client.Latitude),
+		Longitude: geo.Degrees(d.Client.Longitude),
+	}, LocationGeoIP)
+	return config
+}
+
+// Config fetches the client's configuration from speedtest.net, retrying
+// transient failures according to the policy carried by ctx.
 func (c *Client) Config(ctx context.Context) (Config, error) {
-	resp, err := c.get(ctx, "https://www.speedtest.net/speedtest-config.php")
-	if err != nil {
+	var document configDocument
+	if err := c.getXML(ctx, configURL, &document); err != nil {
 		return Config{}, err
 	}
+	return document.config(), nil
+}
 
-	document := struct {
-		Client struct {
-			Config
-			Latitude  float64 `xml:"lat,attr"`
-			Longitude float64 `xml:"lon,attr"`
-		} `xml:"client"`
-	}{}
-
-	if err = resp.ReadXML(&document); err != nil {
+// LoadConfigFile reads a configuration saved from speedtest-config.php, for
+// reproducible runs or where speedtest.net discovery is blocked.
+func LoadConfigFile(path string) (Config, error) {
+	var document configDocument
+	if err := readXMLFile(path, &document); err != nil {
 		return Config{}, err
 	}
+	return document.config(), nil
+}
 
-	config := document.Client.Config
-	config.Coordinates = geo.Coordinates{
-		Latitude:  geo.Degrees(document.Client.Latitude),
-		Longitude: geo.Degrees(document.Client.Longitude),
+func readXMLFile(path string, out interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
 	}
-
-	return config, nil
+	if err := xml.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to parse %q: %v", path, err)
+	}
+	return nil
 }
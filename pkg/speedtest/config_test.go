@@ -2,6 +2,8 @@ package speedtest
 
 import (
 	"context"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -20,3 +22,19 @@ func TestClient_Config(t *testing.T) {
 
 	t.Log(cfg)
 }
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.xml")
+	doc := `<settings><client ip="192.0.2.1" lat="1.5" lon="2.5" isp="Test ISP" /></settings>`
+	if err := ioutil.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.IP != "192.0.2.1" || cfg.Coordinates.Latitude != 1.5 || cfg.LocationSource != LocationGeoIP {
+		t.Errorf("got: %+v", cfg)
+	}
+}
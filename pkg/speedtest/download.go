@@ -23,6 +23,16 @@ func (s Server) ProbeDownloadSpeed(
 	client *Client,
 	stream chan<- units.BytesPerSecond,
 ) (units.BytesPerSecond, error) {
+	t, err := s.ProbeDownload(ctx, client, stream)
+	return t.Speed, err
+}
+
+// ProbeDownload is like ProbeDownloadSpeed but also reports how much was transferred.
+func (s Server) ProbeDownload(
+	ctx context.Context,
+	client *Client,
+	stream chan<- units.BytesPerSecond,
+) (Transfer, error) {
 	grp := prober.NewGroup(concurrentDownloadLimit)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -32,7 +42,7 @@ func (s Server) ProbeDownloadSpeed(
 			url, err := s.RelativeURL(
 				fmt.Sprintf("random%dx%d.jpg", size, size))
 			if err != nil {
-				return 0, fmt.Errorf("error parsing url for %v: %v", s, err)
+				return Transfer{}, fmt.Errorf("error parsing url for %v: %v", s, err)
 			}
 
 			grp.Add(func() (prober.BytesTransferred, error) {
@@ -41,7 +51,17 @@ func (s Server) ProbeDownloadSpeed(
 		}
 	}
 
-	return proberutil.SpeedCollect(grp, stream)
+	speed, b, err := proberutil.SpeedCollect(grp, stream)
+	return Transfer{Speed: speed, Bytes: int64(b)}, err
+}
+
+// Transfer is the outcome of a speed probe.
+type Transfer struct {
+	// Speed is the average speed over the probe.
+	Speed units.BytesPerSecond
+
+	// Bytes is how much the probe requests transferred.
+	Bytes int64
 }
 
 func (c *Client) downloadFile(
@@ -0,0 +1,187 @@
+package speedtest
+
+import (
+	"fmt"
+	"framey/assignment/internal/geo"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter narrows down the servers considered for a test. The zero Filter
+// matches every server.
+type Filter struct {
+	// Countries lists the country codes (Server.CC) to accept, compared
+	// case-insensitively.
+	Countries []string
+
+	// Sponsor and Name, if set, must match the server's sponsor and name (its
+	// city) respectively.
+	Sponsor *regexp.Regexp
+	Name    *regexp.Regexp
+
+	// MaxDistance is the furthest a server may be from the client. Zero means
+	// any distance.
+	MaxDistance geo.Kilometers
+
+	// Allow, if not empty, lists the only servers to accept. Block lists
+	// servers to reject.
+	Allow []ServerID
+	Block []ServerID
+}
+
+// NeedsOrigin reports whether the filter depends on the client's location.
+func (f Filter) NeedsOrigin() bool {
+	return f.MaxDistance > 0
+}
+
+// Match reports whether s passes the filter for a client at origin.
+func (f Filter) Match(s Server, origin geo.Coordinates) bool {
+	if len(f.Countries) != 0 && !containsFold(f.Countries, s.CC) {
+		return false
+	}
+	if f.Sponsor != nil && !f.Sponsor.MatchString(s.Sponsor) {
+		return false
+	}
+	if f.Name != nil && !f.Name.MatchString(s.Name) {
+		return false
+	}
+	if f.MaxDistance > 0 && f.MaxDistance.Less(origin.DistanceTo(s.Coordinates)) {
+		return false
+	}
+	if len(f.Allow) != 0 && !containsID(f.Allow, s.ID) {
+		return false
+	}
+	return !containsID(f.Block, s.ID)
+}
+
+// Apply returns the servers passing the filter for a client at origin, in
+// their original order.
+func (f Filter) Apply(servers []Server, origin geo.Coordinates) []Server {
+	n := make([]Server, 0, len(servers))
+	for _, s := range servers {
+		if f.Match(s, origin) {
+			n = append(n, s)
+		}
+	}
+	return n
+}
+
+// ApplyIndex is like Apply but only looks at servers within MaxDistance, if
+// set, and returns them closest first.
+func (f Filter) ApplyIndex(x *ServerIndex, origin geo.Coordinates) []Server {
+	if f.MaxDistance <= 0 {
+		return f.Apply(x.Servers(), origin)
+	}
+	// Match checks the distance again, which keeps the rounding of both
+	// distance computations from disagreeing at the boundary.
+	return f.Apply(x.Within(origin, f.MaxDistance), origin)
+}
+
+// Narrow returns the subset of x passing the filter for a client at origin,
+// for selectors to query without indexing the servers again.
+func (f Filter) Narrow(x *ServerIndex, origin geo.Coordinates) *ServerIndex {
+	return x.Subset(func(s Server) bool { return f.Match(s, origin) })
+}
+
+func containsFold(l []string, s string) bool {
+	for _, e := range l {
+		if strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(l []ServerID, id ServerID) bool {
+	for _, e := range l {
+		if e == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilter parses a filter expression made of terms separated by
+// semicolons, all of which must hold:
+//
+//	cc=US,CA        country code is one of the list
+//	sponsor=text    sponsor contains text, ignoring case
+//	sponsor~regexp  sponsor matches regexp
+//	name=text       name (city) contains text, ignoring case
+//	name~regexp     name (city) matches regexp
+//	radius=500      at most 500 km away from the client
+//	allow=1,2       server ID is one of the list
+//	block=3,4       server ID is not one of the list
+//
+// For example: "cc=DE,NL; sponsor~(?i)telekom; radius=800".
+func ParseFilter(expr string) (Filter, error) {
+	var f Filter
+	for _, term := range strings.Split(expr, ";") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		i := strings.IndexAny(term, "=~")
+		if i < 0 {
+			return Filter{}, fmt.Errorf("filter term %q has no operator", term)
+		}
+		key, op, val := strings.TrimSpace(term[:i]), term[i], strings.TrimSpace(term[i+1:])
+
+		var err error
+		switch {
+		case key == "cc" && op == '=':
+			f.Countries = splitList(val)
+		case key == "sponsor":
+			f.Sponsor, err = parseMatcher(op, val)
+		case key == "name":
+			f.Name, err = parseMatcher(op, val)
+		case key == "radius" && op == '=':
+			var km float64
+			km, err = strconv.ParseFloat(strings.TrimSuffix(val, "km"), 64)
+			if err == nil && km <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+			f.MaxDistance = geo.Kilometers(km)
+		case key == "allow" && op == '=':
+			f.Allow, err = parseIDs(val)
+		case key == "block" && op == '=':
+			f.Block, err = parseIDs(val)
+		default:
+			err = fmt.Errorf("unknown key or operator")
+		}
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid filter term %q: %v", term, err)
+		}
+	}
+	return f, nil
+}
+
+func parseMatcher(op byte, val string) (*regexp.Regexp, error) {
+	if op == '=' {
+		val = "(?i)" + regexp.QuoteMeta(val)
+	}
+	return regexp.Compile(val)
+}
+
+func splitList(val string) []string {
+	var l []string
+	for _, e := range strings.Split(val, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+func parseIDs(val string) ([]ServerID, error) {
+	var ids []ServerID
+	for _, e := range splitList(val) {
+		n, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, ServerID(n))
+	}
+	return ids, nil
+}
@@ -0,0 +1,62 @@
+package speedtest
+
+import (
+	"framey/assignment/internal/geo"
+	"testing"
+)
+
+var filterTestServers = []Server{
+	{ID: 1, Name: "Amsterdam", CC: "NL", Sponsor: "Acme Fiber",
+		Coordinates: geo.Coordinates{Latitude: 52.37, Longitude: 4.90}},
+	{ID: 2, Name: "Frankfurt", CC: "DE", Sponsor: "Deutsche Telekom",
+		Coordinates: geo.Coordinates{Latitude: 50.11, Longitude: 8.68}},
+	{ID: 3, Name: "New York", CC: "US", Sponsor: "Acme Cable",
+		Coordinates: geo.Coordinates{Latitude: 40.71, Longitude: -74.01}},
+}
+
+// Somewhere in Brussels.
+var filterTestOrigin = geo.Coordinates{Latitude: 50.85, Longitude: 4.35}
+
+func TestParseFilter(t *testing.T) {
+	for expr, expected := range map[string][]ServerID{
+		"":                           {1, 2, 3},
+		"cc=nl,DE":                   {1, 2},
+		"sponsor=acme":               {1, 3},
+		"sponsor~^Acme F":            {1},
+		"name=york":                  {3},
+		"radius=500":                 {1, 2},
+		"radius=500km; block=2":      {1},
+		"allow=2,3":                  {2, 3},
+		" cc=US ; sponsor = acme ; ": {3},
+	} {
+		f, err := ParseFilter(expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", expr, err)
+			continue
+		}
+		got := f.Apply(filterTestServers, filterTestOrigin)
+		if len(got) != len(expected) {
+			t.Errorf("%q: got %v", expr, got)
+			continue
+		}
+		for i, s := range got {
+			if s.ID != expected[i] {
+				t.Errorf("%q: got %v", expr, got)
+			}
+		}
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{"cc", "country=US", "sponsor~(", "radius=-5", "radius=far", "allow=a", "cc~US"} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestFilter_NeedsOrigin(t *testing.T) {
+	if (Filter{}).NeedsOrigin() || !(Filter{MaxDistance: 1}).NeedsOrigin() {
+		t.Fail()
+	}
+}
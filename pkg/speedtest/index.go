@@ -0,0 +1,89 @@
+package speedtest
+
+import (
+	"framey/assignment/internal/geo"
+)
+
+// ServerIndex answers proximity queries over a server list. Building one is
+// about as expensive as sorting the list by distance once, so it pays off
+// when the same list is queried repeatedly, e.g. by a daemon.
+//
+// Narrowing an index down with Subset shares the work done building it.
+type ServerIndex struct {
+	servers []Server
+	ix      *geo.Index
+
+	// keep marks the servers in a subset, nil if every server is in it.
+	keep []bool
+	n    int
+}
+
+// NewServerIndex indexes a copy of servers.
+func NewServerIndex(servers []Server) *ServerIndex {
+	servers = append([]Server(nil), servers...)
+	points := make([]geo.Coordinates, len(servers))
+	for i, s := range servers {
+		points[i] = s.Coordinates
+	}
+	return &ServerIndex{servers: servers, ix: geo.NewIndex(points), n: len(servers)}
+}
+
+// Subset returns an index over the servers of x that keep returns true for,
+// without indexing them again.
+func (x *ServerIndex) Subset(keep func(Server) bool) *ServerIndex {
+	sub := &ServerIndex{servers: x.servers, ix: x.ix, keep: make([]bool, len(x.servers))}
+	for i, s := range x.servers {
+		if x.has(i) && keep(s) {
+			sub.keep[i] = true
+			sub.n++
+		}
+	}
+	return sub
+}
+
+func (x *ServerIndex) has(i int) bool {
+	return x.keep == nil || x.keep[i]
+}
+
+// Servers returns a copy of the indexed servers in their original order.
+func (x *ServerIndex) Servers() []Server {
+	s := make([]Server, 0, x.n)
+	for i := range x.servers {
+		if x.has(i) {
+			s = append(s, x.servers[i])
+		}
+	}
+	return s
+}
+
+// Len returns the number of indexed servers.
+func (x *ServerIndex) Len() int {
+	return x.n
+}
+
+// Closest returns the n servers closest to origin, closest first.
+func (x *ServerIndex) Closest(origin geo.Coordinates, n int) []Server {
+	if x.keep == nil {
+		return x.lookup(x.ix.Nearest(origin, n))
+	}
+	return x.lookup(x.ix.NearestFunc(origin, n, x.has))
+}
+
+// Within returns the servers at most r away from origin, closest first.
+func (x *ServerIndex) Within(origin geo.Coordinates, r geo.Kilometers) []Server {
+	var idx []int
+	for _, i := range x.ix.Within(origin, r) {
+		if x.has(i) {
+			idx = append(idx, i)
+		}
+	}
+	return x.lookup(idx)
+}
+
+func (x *ServerIndex) lookup(idx []int) []Server {
+	s := make([]Server, len(idx))
+	for i, j := range idx {
+		s[i] = x.servers[j]
+	}
+	return s
+}
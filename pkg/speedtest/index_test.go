@@ -0,0 +1,58 @@
+package speedtest
+
+import (
+	"framey/assignment/internal/geo"
+	"testing"
+)
+
+func TestServerIndex_Closest(t *testing.T) {
+	x := NewServerIndex(filterTestServers)
+	if x.Len() != len(filterTestServers) {
+		t.Fatalf("got %d servers", x.Len())
+	}
+
+	closest := x.Closest(filterTestOrigin, 2)
+	if len(closest) != 2 || closest[0].ID != 1 || closest[1].ID != 2 {
+		t.Errorf("got: %v", closest)
+	}
+
+	within := x.Within(filterTestOrigin, 500)
+	if len(within) != 2 || within[0].ID != 1 || within[1].ID != 2 {
+		t.Errorf("got: %v", within)
+	}
+}
+
+func TestFilter_ApplyIndex(t *testing.T) {
+	x := NewServerIndex(filterTestServers)
+	for _, f := range []Filter{
+		{},
+		{MaxDistance: 500},
+		{MaxDistance: geo.Kilometers(10000), Sponsor: nil, Block: []ServerID{1}},
+	} {
+		expected := f.Apply(filterTestServers, filterTestOrigin)
+		got := f.ApplyIndex(x, filterTestOrigin)
+		if len(got) != len(expected) {
+			t.Errorf("%+v: expected %v, got %v", f, expected, got)
+		}
+	}
+}
+
+func TestFilter_Narrow(t *testing.T) {
+	x := NewServerIndex(filterTestServers)
+	f := Filter{Block: []ServerID{1}}
+	sub := f.Narrow(x, filterTestOrigin)
+
+	expected := f.Apply(filterTestServers, filterTestOrigin)
+	if sub.Len() != len(expected) || len(sub.Servers()) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, sub.Servers())
+	}
+	if closest := sub.Closest(filterTestOrigin, 1); len(closest) != 1 || closest[0].ID != 2 {
+		t.Errorf("got: %v", closest)
+	}
+	if within := sub.Within(filterTestOrigin, 500); len(within) != 1 || within[0].ID != 2 {
+		t.Errorf("got: %v", within)
+	}
+	if one := sub.Subset(func(s Server) bool { return s.ID <= 2 }); one.Len() != 1 {
+		t.Errorf("got: %v", one.Servers())
+	}
+}
@@ -118,20 +118,62 @@ func (s Server) AverageLatency(
 	client *Client,
 	samples int,
 ) (time.Duration, error) {
+	ds, err := s.LatencySamples(ctx, client, samples)
+	if err != nil {
+		return time.Duration(0), err
+	}
+	return mean(ds), nil
+}
+
+// Takes samples of a server's latency, serialized and failing fast like
+// AverageLatency.
+//
+func (s Server) LatencySamples(
+	ctx context.Context,
+	client *Client,
+	samples int,
+) ([]time.Duration, error) {
 	if samples <= 0 {
 		panic("must have samples > 0")
 	}
 
-	var total time.Duration
-	for i := 0; i < samples; i++ {
-		if d, err := s.Latency(ctx, client); err != nil {
-			return time.Duration(0), err
-		} else {
-			total += d
+	ds := make([]time.Duration, samples)
+	for i := range ds {
+		d, err := s.Latency(ctx, client)
+		if err != nil {
+			return nil, err
 		}
+		ds[i] = d
 	}
+	return ds, nil
+}
+
+func mean(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+	return total / time.Duration(len(ds))
+}
 
-	return total / time.Duration(samples), nil
+// Jitter is the mean absolute difference between consecutive samples, as
+// speedtest.net reports it.
+func jitter(ds []time.Duration) time.Duration {
+	if len(ds) < 2 {
+		return 0
+	}
+	var total time.Duration
+	for i := 1; i < len(ds); i++ {
+		d := ds[i] - ds[i-1]
+		if d < 0 {
+			d = -d
+		}
+		total += d
+	}
+	return total / time.Duration(len(ds)-1)
 }
 
 func (s Server) Latency(
@@ -2,6 +2,7 @@ package speedtest
 
 import (
 	"context"
+	"framey/assignment/pkg/timing"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
@@ -100,3 +101,19 @@ func TestServer_Latency(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestServer_Latency_RecordsTimings(t *testing.T) {
+	const expectedLatency = 10 * time.Millisecond
+	ts := newLatencyTestServer(expectedLatency)
+	defer ts.Close()
+	s := Server{URL: ts.URL}
+
+	var rec timing.Recorder
+	ctx := timing.WithRecorder(context.Background(), &rec)
+	if _, err := s.Latency(ctx, &Client{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ttfb := rec.Stats(timing.TTFB); ttfb.Count != 1 || ttfb.Max < expectedLatency {
+		t.Errorf("Unexpected time to first byte: %+v", ttfb)
+	}
+}
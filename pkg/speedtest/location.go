@@ -0,0 +1,60 @@
+package speedtest
+
+import (
+	"fmt"
+	"framey/assignment/internal/geo"
+	"strings"
+)
+
+// LocationSource tells where the client coordinates in a Config came from.
+type LocationSource string
+
+const (
+	// LocationGeoIP is speedtest.net's geolocation of the client's IP.
+	LocationGeoIP LocationSource = "speedtest.net"
+	// LocationManual is coordinates given by the user.
+	LocationManual LocationSource = "manual"
+	// LocationCity is a city looked up in the server list.
+	LocationCity LocationSource = "city"
+)
+
+// Locate overrides the client coordinates, e.g. because speedtest.net's
+// geolocation is wrong for clients behind VPNs or satellite links.
+func (c *Config) Locate(coords geo.Coordinates, source LocationSource) {
+	c.Coordinates = coords
+	c.LocationSource = source
+}
+
+// LocateCity looks a city up in the server list, since servers are named
+// after the city they are in, and returns the average coordinates of the
+// servers there. The city may be qualified by a country or country code, as in
+// "Paris, FR", to tell apart cities of the same name.
+func LocateCity(servers []Server, city string) (geo.Coordinates, error) {
+	name, country := strings.TrimSpace(city), ""
+	if i := strings.LastIndex(name, ","); i >= 0 {
+		name, country = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+	}
+
+	var (
+		lat, lon float64
+		n        int
+	)
+	for _, s := range servers {
+		if !strings.EqualFold(s.Name, name) {
+			continue
+		}
+		if country != "" && !strings.EqualFold(s.CC, country) && !strings.EqualFold(s.Country, country) {
+			continue
+		}
+		lat += float64(s.Coordinates.Latitude)
+		lon += float64(s.Coordinates.Longitude)
+		n++
+	}
+	if n == 0 {
+		return geo.Coordinates{}, fmt.Errorf("no server in a city called %q", city)
+	}
+	return geo.Coordinates{
+		Latitude:  geo.Degrees(lat / float64(n)),
+		Longitude: geo.Degrees(lon / float64(n)),
+	}, nil
+}
@@ -0,0 +1,35 @@
+package speedtest
+
+import (
+	"framey/assignment/internal/geo"
+	"testing"
+)
+
+func TestLocateCity(t *testing.T) {
+	servers := []Server{
+		{Name: "Paris", Country: "France", CC: "FR", Coordinates: geo.Coordinates{Latitude: 48, Longitude: 2}},
+		{Name: "Paris", Country: "France", CC: "FR", Coordinates: geo.Coordinates{Latitude: 49, Longitude: 3}},
+		{Name: "Paris", Country: "United States", CC: "US", Coordinates: geo.Coordinates{Latitude: 33.66, Longitude: -95.56}},
+		{Name: "Lyon", Country: "France", CC: "FR", Coordinates: geo.Coordinates{Latitude: 45.76, Longitude: 4.84}},
+	}
+
+	for city, expected := range map[string]geo.Coordinates{
+		"paris, fr":     {Latitude: 48.5, Longitude: 2.5},
+		"Paris, France": {Latitude: 48.5, Longitude: 2.5},
+		"Paris,US":      {Latitude: 33.66, Longitude: -95.56},
+		" Lyon ":        {Latitude: 45.76, Longitude: 4.84},
+	} {
+		c, err := LocateCity(servers, city)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", city, err)
+		} else if c.String() != expected.String() {
+			t.Errorf("%q: got %v", city, c)
+		}
+	}
+
+	for _, city := range []string{"Berlin", "Lyon, DE"} {
+		if _, err := LocateCity(servers, city); err == nil {
+			t.Errorf("%q: expected an error", city)
+		}
+	}
+}
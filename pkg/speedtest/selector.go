@@ -0,0 +1,251 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"framey/assignment/internal/geo"
+	"framey/assignment/internal/units"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCandidates is how many of the closest servers the latency based
+// selectors consider by default.
+const DefaultCandidates = 5
+
+// Selection is a server chosen for a test along with what's known about it.
+type Selection struct {
+	Server   Server
+	Distance geo.Kilometers
+	Latency  time.Duration
+	Jitter   time.Duration
+}
+
+// Selector chooses the server to test against among servers, for a client at
+// origin.
+type Selector interface {
+	Select(
+		ctx context.Context,
+		client *Client,
+		origin geo.Coordinates,
+		servers *ServerIndex,
+	) (Selection, error)
+}
+
+// Closest selects the closest server that answers a latency probe.
+type Closest struct{}
+
+func (Closest) Select(
+	ctx context.Context,
+	client *Client,
+	origin geo.Coordinates,
+	servers *ServerIndex,
+) (Selection, error) {
+	var lastErr error
+	for _, s := range servers.Closest(origin, servers.Len()) {
+		samples, err := s.LatencySamples(ctx, client, DefaultLatencySamples)
+		if err == nil {
+			return selectionOf(s, origin.DistanceTo(s.Coordinates), samples), nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return Selection{}, noServer(lastErr)
+}
+
+// LowestLatency selects the server with the lowest average latency among the
+// closest Candidates, DefaultCandidates if zero.
+type LowestLatency struct {
+	Candidates int
+}
+
+func (sel LowestLatency) Select(
+	ctx context.Context,
+	client *Client,
+	origin geo.Coordinates,
+	servers *ServerIndex,
+) (Selection, error) {
+	return selectBy(ctx, client, origin, servers, sel.Candidates,
+		best(func(a, b Selection) bool { return a.Latency < b.Latency }))
+}
+
+// LowestJitter selects the server whose latency varies the least among the
+// closest Candidates, DefaultCandidates if zero. Ties are broken by latency.
+type LowestJitter struct {
+	Candidates int
+}
+
+func (sel LowestJitter) Select(
+	ctx context.Context,
+	client *Client,
+	origin geo.Coordinates,
+	servers *ServerIndex,
+) (Selection, error) {
+	return selectBy(ctx, client, origin, servers, sel.Candidates,
+		best(func(a, b Selection) bool {
+			if a.Jitter != b.Jitter {
+				return a.Jitter < b.Jitter
+			}
+			return a.Latency < b.Latency
+		}))
+}
+
+// ThroughputHistory reports the download speed previously achieved against a
+// server.
+type ThroughputHistory interface {
+	Throughput(id ServerID) (units.BytesPerSecond, bool)
+}
+
+// BestThroughput selects the server with the best download speed in History
+// that still answers a latency probe. Servers without history are left to
+// Fallback, LowestLatency if nil.
+type BestThroughput struct {
+	History  ThroughputHistory
+	Fallback Selector
+}
+
+func (sel BestThroughput) Select(
+	ctx context.Context,
+	client *Client,
+	origin geo.Coordinates,
+	servers *ServerIndex,
+) (Selection, error) {
+	var known []Server
+	for _, s := range servers.Servers() {
+		if _, ok := sel.History.Throughput(s.ID); ok {
+			known = append(known, s)
+		}
+	}
+	sort.SliceStable(known, func(i, j int) bool {
+		a, _ := sel.History.Throughput(known[i].ID)
+		b, _ := sel.History.Throughput(known[j].ID)
+		return a > b
+	})
+
+	for _, s := range known {
+		samples, err := s.LatencySamples(ctx, client, DefaultLatencySamples)
+		if err == nil {
+			return selectionOf(s, origin.DistanceTo(s.Coordinates), samples), nil
+		}
+		if ctx.Err() != nil {
+			return Selection{}, noServer(err)
+		}
+	}
+
+	fallback := sel.Fallback
+	if fallback == nil {
+		fallback = LowestLatency{}
+	}
+	return fallback.Select(ctx, client, origin, servers)
+}
+
+// RandomTopK selects a random server among the K closest ones that answer a
+// latency probe, spreading the load of many clients over several servers.
+// K defaults to DefaultCandidates. Rand defaults to the global source.
+type RandomTopK struct {
+	K    int
+	Rand *rand.Rand
+}
+
+func (sel RandomTopK) Select(
+	ctx context.Context,
+	client *Client,
+	origin geo.Coordinates,
+	servers *ServerIndex,
+) (Selection, error) {
+	intn := rand.Intn
+	if sel.Rand != nil {
+		intn = sel.Rand.Intn
+	}
+	return selectBy(ctx, client, origin, servers, sel.K,
+		func(good []Selection) Selection {
+			return good[intn(len(good))]
+		})
+}
+
+// Returns a pick function for selectBy choosing the first selection according
+// to less, or the closest one among equals.
+func best(less func(a, b Selection) bool) func([]Selection) Selection {
+	return func(good []Selection) Selection {
+		sort.SliceStable(good, func(i, j int) bool {
+			return less(good[i], good[j])
+		})
+		return good[0]
+	}
+}
+
+// Probes the closest candidates concurrently and lets pick choose among those
+// that answered, which are passed sorted by distance.
+func selectBy(
+	ctx context.Context,
+	client *Client,
+	origin geo.Coordinates,
+	servers *ServerIndex,
+	candidates int,
+	pick func(good []Selection) Selection,
+) (Selection, error) {
+	if candidates <= 0 {
+		candidates = DefaultCandidates
+	}
+	closest := servers.Closest(origin, candidates)
+
+	type result struct {
+		sel Selection
+		err error
+	}
+	c := make(chan result, len(closest))
+	var g sync.WaitGroup
+	for i := range closest {
+		s := closest[i]
+		g.Add(1)
+		go func() {
+			defer g.Done()
+			samples, err := s.LatencySamples(ctx, client, DefaultLatencySamples)
+			c <- result{selectionOf(s, origin.DistanceTo(s.Coordinates), samples), err}
+		}()
+	}
+	g.Wait()
+	close(c)
+
+	var (
+		good    []Selection
+		lastErr error
+	)
+	for r := range c {
+		if r.err != nil {
+			lastErr = r.err
+			continue
+		}
+		good = append(good, r.sel)
+	}
+	if len(good) == 0 {
+		return Selection{}, noServer(lastErr)
+	}
+
+	// Keep the outcome deterministic despite the probes finishing in any
+	// order.
+	sort.Slice(good, func(i, j int) bool {
+		return good[i].Distance.Less(good[j].Distance)
+	})
+	return pick(good), nil
+}
+
+func selectionOf(s Server, d geo.Kilometers, samples []time.Duration) Selection {
+	return Selection{
+		Server:   s,
+		Distance: d,
+		Latency:  mean(samples),
+		Jitter:   jitter(samples),
+	}
+}
+
+func noServer(err error) error {
+	if err == nil {
+		return fmt.Errorf("no servers to select from")
+	}
+	return fmt.Errorf("no server answered: %v", err)
+}
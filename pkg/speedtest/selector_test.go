@@ -0,0 +1,128 @@
+package speedtest
+
+import (
+	"context"
+	"framey/assignment/internal/geo"
+	"framey/assignment/internal/units"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const selectorTimeScale = 10 * time.Millisecond
+
+// Returns a server at the given latitude, on the same meridian as the origin
+// of the selector tests, so that lower latitudes are closer.
+func newSelectorTestServer(t *testing.T, id ServerID, lat geo.Degrees, latency time.Duration) Server {
+	ts := newLatencyTestServer(latency)
+	t.Cleanup(ts.Close)
+	return Server{ID: id, URL: ts.URL, Coordinates: geo.Coordinates{Latitude: lat}}
+}
+
+// Alternates between no delay and the given latency.
+func newJitteryTestServer(t *testing.T, id ServerID, lat geo.Degrees, latency time.Duration) Server {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1)%2 == 0 {
+			time.Sleep(latency)
+		}
+		w.Write([]byte("test=test"))
+	}))
+	t.Cleanup(ts.Close)
+	return Server{ID: id, URL: ts.URL, Coordinates: geo.Coordinates{Latitude: lat}}
+}
+
+func brokenTestServer(id ServerID, lat geo.Degrees) Server {
+	return Server{ID: id, URL: "http://127.0.0.1:1/", Coordinates: geo.Coordinates{Latitude: lat}}
+}
+
+type mapHistory map[ServerID]units.BytesPerSecond
+
+func (h mapHistory) Throughput(id ServerID) (units.BytesPerSecond, bool) {
+	b, ok := h[id]
+	return b, ok
+}
+
+func TestSelectors(t *testing.T) {
+	var (
+		broken  = brokenTestServer(1, 1)
+		slow    = newSelectorTestServer(t, 2, 2, 3*selectorTimeScale)
+		fast    = newSelectorTestServer(t, 3, 3, selectorTimeScale)
+		jittery = newJitteryTestServer(t, 4, 4, selectorTimeScale)
+		far     = newSelectorTestServer(t, 5, 50, 0)
+		servers = []Server{far, jittery, fast, slow, broken}
+	)
+
+	for name, tc := range map[string]struct {
+		sel      Selector
+		expected []ServerID
+	}{
+		"closest":            {Closest{}, []ServerID{slow.ID}},
+		"latency":            {LowestLatency{Candidates: 4}, []ServerID{jittery.ID}},
+		"latency, 3 closest": {LowestLatency{Candidates: 3}, []ServerID{fast.ID}},
+		"latency, all":       {LowestLatency{Candidates: 5}, []ServerID{far.ID}},
+		"jitter":             {LowestJitter{Candidates: 4}, []ServerID{slow.ID, fast.ID}},
+		"throughput":         {BestThroughput{History: mapHistory{broken.ID: 100, fast.ID: 10, slow.ID: 1}}, []ServerID{fast.ID}},
+		"throughput, none":   {BestThroughput{History: mapHistory{}, Fallback: Closest{}}, []ServerID{slow.ID}},
+	} {
+		sel, err := tc.sel.Select(context.Background(), &Client{}, geo.Coordinates{}, NewServerIndex(servers))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if !containsID(tc.expected, sel.Server.ID) {
+			t.Errorf("%s: expected one of servers %v, got %+v", name, tc.expected, sel)
+		}
+		if sel.Latency <= 0 || sel.Distance <= 0 {
+			t.Errorf("%s: incomplete selection %+v", name, sel)
+		}
+	}
+
+	if servers[0].ID != far.ID || servers[4].ID != broken.ID {
+		t.Error("Selectors modified the server list")
+	}
+}
+
+func TestRandomTopK(t *testing.T) {
+	servers := []Server{
+		newSelectorTestServer(t, 1, 1, 0),
+		newSelectorTestServer(t, 2, 2, 0),
+		brokenTestServer(3, 3),
+		newSelectorTestServer(t, 4, 4, 0),
+	}
+
+	sel := RandomTopK{K: 3, Rand: rand.New(rand.NewSource(time.Now().Unix()))}
+	seen := make(map[ServerID]bool)
+	for i := 0; i < 20; i++ {
+		s, err := sel.Select(context.Background(), &Client{}, geo.Coordinates{}, NewServerIndex(servers))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		seen[s.Server.ID] = true
+	}
+	if seen[3] || seen[4] {
+		t.Errorf("Selected a broken or too distant server: %v", seen)
+	}
+}
+
+func TestSelectors_NoServerAnswers(t *testing.T) {
+	servers := []Server{brokenTestServer(1, 1), brokenTestServer(2, 2)}
+	for _, sel := range []Selector{Closest{}, LowestLatency{}, LowestJitter{}, RandomTopK{}} {
+		if _, err := sel.Select(context.Background(), &Client{}, geo.Coordinates{}, NewServerIndex(servers)); err == nil {
+			t.Errorf("%T: expected an error", sel)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	ds := []time.Duration{10, 30, 20, 20}
+	if j := jitter(ds); j != 10 {
+		t.Errorf("got: %v", j)
+	}
+	if m := mean(ds); m != 20 {
+		t.Errorf("got: %v", m)
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"framey/assignment/internal/geo"
-	"golang.org/x/sync/errgroup"
 	"net/url"
 	"sort"
+	"strings"
 )
 
 type ServerID uint64
@@ -48,73 +48,83 @@ var serverURLs = []string{
 	"https://c.speedtest.net/speedtest-servers.php",
 }
 
+// LoadAllServers asks every server list mirror in parallel and returns the
+// first list to arrive, deduplicated and sorted by ID. It only fails if every
+// mirror does. Each mirror is retried according to the policy carried by ctx.
 func (c *Client) LoadAllServers(ctx context.Context) ([]Server, error) {
-	grp, ctx := errgroup.WithContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Spread.
-	ch := make(chan []Server)
+	type result struct {
+		servers []Server
+		err     error
+	}
+
+	// Buffered so that the losing mirrors don't block once we've returned.
+	ch := make(chan result, len(serverURLs))
 	for _, u := range serverURLs {
-		grp.Go(func() error {
-			if s, err := c.loadServersFrom(ctx, u); err != nil {
-				return err
-			} else {
-				ch <- s
-				return nil
-			}
-		})
+		u := u
+		go func() {
+			s, err := c.loadServersFrom(ctx, u)
+			ch <- result{s, err}
+		}()
 	}
 
-	// Collect.
-	var servers []Server
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case s := <-ch:
-				servers = append(servers, s...)
-			}
+	errs := make([]string, 0, len(serverURLs))
+	for range serverURLs {
+		r := <-ch
+		if r.err == nil {
+			return dedupAndSort(r.servers), nil
 		}
-	}()
-
-	if err := grp.Wait(); err != nil {
-		return nil, err
-	} else {
-		return dedupAndSort(servers), nil
+		errs = append(errs, r.err.Error())
 	}
+	return nil, fmt.Errorf("every server list mirror failed: %s", strings.Join(errs, "; "))
 }
 
-func (c *Client) loadServersFrom(ctx context.Context, url string) ([]Server, error) {
-	res, err := c.get(ctx, url)
-	if res != nil {
-		url = res.Request.URL.String()
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve server list from %q: %v", url, err)
-	}
-
-	doc := struct {
-		List []struct {
-			Server
-			Latitude  float64 `xml:"lat,attr"`
-			Longitude float64 `xml:"lon,attr"`
-		} `xml:"servers>server"`
-	}{}
-
-	if err = res.ReadXML(&doc); err != nil {
-		return nil, fmt.Errorf("failed to parse server list from %q: %v", url, err)
-	}
+// A speedtest-servers.php document.
+type serverListDocument struct {
+	List []struct {
+		Server
+		Latitude  float64 `xml:"lat,attr"`
+		Longitude float64 `xml:"lon,attr"`
+	} `xml:"servers>server"`
+}
 
-	servers := make([]Server, len(doc.List))
-	for i, s := range doc.List {
+func (d *serverListDocument) servers() []Server {
+	servers := make([]Server, len(d.List))
+	for i, s := range d.List {
 		s.Server.Coordinates = geo.Coordinates{
 			Latitude:  geo.Degrees(s.Latitude),
 			Longitude: geo.Degrees(s.Longitude),
 		}
 		servers[i] = s.Server
 	}
+	return servers
+}
+
+func (c *Client) loadServersFrom(ctx context.Context, url string) ([]Server, error) {
+	var doc serverListDocument
+	if err := c.getXML(ctx, url, &doc); err != nil {
+		return nil, fmt.Errorf("failed to load server list from %q: %v", url, err)
+	}
+	if len(doc.List) == 0 {
+		return nil, fmt.Errorf("server list from %q is empty", url)
+	}
+	return doc.servers(), nil
+}
 
-	return servers, nil
+// LoadServersFile reads a server list in the format of speedtest-servers.php,
+// deduplicated and sorted by ID like LoadAllServers. This allows pinning a
+// known set of servers for reproducible benchmarks.
+func LoadServersFile(path string) ([]Server, error) {
+	var doc serverListDocument
+	if err := readXMLFile(path, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.List) == 0 {
+		return nil, fmt.Errorf("server list in %q is empty", path)
+	}
+	return dedupAndSort(doc.servers()), nil
 }
 
 func dedupAndSort(servers []Server) []Server {
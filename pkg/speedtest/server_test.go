@@ -0,0 +1,97 @@
+package speedtest
+
+import (
+	"context"
+	"framey/assignment/pkg/retry"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testServerList = `<?xml version="1.0" encoding="UTF-8"?>
+<settings>
+<servers>
+<server url="http://a.example:8080/speedtest/upload.php" lat="52.3667" lon="4.9000" name="Amsterdam" country="Netherlands" cc="NL" sponsor="Sponsor A" id="2" host="a.example:8080" />
+<server url="http://b.example:8080/speedtest/upload.php" lat="50.1109" lon="8.6821" name="Frankfurt" country="Germany" cc="DE" sponsor="Sponsor B" id="1" host="b.example:8080" />
+<server url="http://a.example:8080/speedtest/upload.php" lat="52.3667" lon="4.9000" name="Amsterdam" country="Netherlands" cc="NL" sponsor="Sponsor A" id="2" host="a.example:8080" />
+</servers>
+</settings>
+`
+
+var testRetryPolicy = retry.Policy{
+	Attempts:        3,
+	BaseDelay:       time.Millisecond,
+	MaxDelay:        time.Millisecond,
+	RetryableStatus: retry.DefaultPolicy.RetryableStatus,
+}
+
+// Swaps the server list mirrors for the duration of a test.
+func withMirrors(t *testing.T, urls ...string) {
+	old := serverURLs
+	serverURLs = urls
+	t.Cleanup(func() { serverURLs = old })
+}
+
+func TestClient_LoadAllServers_AnyMirror(t *testing.T) {
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	var calls int
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(testServerList))
+	}))
+	defer flaky.Close()
+
+	withMirrors(t, broken.URL, flaky.URL)
+
+	ctx := retry.WithPolicy(context.Background(), testRetryPolicy)
+	servers, err := new(Client).LoadAllServers(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(servers) != 2 || servers[0].ID != 1 || servers[1].ID != 2 {
+		t.Errorf("Expected 2 deduplicated and sorted servers, got %v", servers)
+	}
+	if servers[1].Coordinates.Latitude != 52.3667 {
+		t.Errorf("Coordinates not parsed: %v", servers[1].Coordinates)
+	}
+}
+
+func TestClient_LoadAllServers_AllMirrorsFail(t *testing.T) {
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	withMirrors(t, broken.URL, broken.URL)
+
+	ctx := retry.WithPolicy(context.Background(), testRetryPolicy)
+	if _, err := new(Client).LoadAllServers(ctx); err == nil {
+		t.Fail()
+	}
+}
+
+func TestLoadServersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "servers.xml")
+	if err := ioutil.WriteFile(path, []byte(testServerList), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, err := LoadServersFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(servers) != 2 || servers[0].ID != 1 || servers[1].Sponsor != "Sponsor A" {
+		t.Errorf("got: %v", servers)
+	}
+
+	if _, err := LoadServersFile(filepath.Join(t.TempDir(), "missing.xml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
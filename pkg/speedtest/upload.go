@@ -26,6 +26,16 @@ func (s Server) ProbeUploadSpeed(
 	client *Client,
 	stream chan<- units.BytesPerSecond,
 ) (units.BytesPerSecond, error) {
+	t, err := s.ProbeUpload(ctx, client, stream)
+	return t.Speed, err
+}
+
+// ProbeUpload is like ProbeUploadSpeed but also reports how much was transferred.
+func (s Server) ProbeUpload(
+	ctx context.Context,
+	client *Client,
+	stream chan<- units.BytesPerSecond,
+) (Transfer, error) {
 	grp := prober.NewGroup(concurrentUploadLimit)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -43,7 +53,8 @@ func (s Server) ProbeUploadSpeed(
 		}
 	}
 
-	return proberutil.SpeedCollect(grp, stream)
+	speed, b, err := proberutil.SpeedCollect(grp, stream)
+	return Transfer{Speed: speed, Bytes: int64(b)}, err
 }
 
 type safeReader struct {
@@ -0,0 +1,218 @@
+// Package timing breaks HTTP requests down into their phases using
+// net/http/httptrace so that slow tests can be attributed to DNS, connection
+// setup, the server or the transfer itself.
+package timing
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type Phase int
+
+const (
+	DNS Phase = iota
+	Connect
+	TLS
+	TTFB
+	Transfer
+
+	numPhases
+)
+
+// Phases lists every phase in the order they happen during a request.
+var Phases = []Phase{DNS, Connect, TLS, TTFB, Transfer}
+
+func (p Phase) String() string {
+	switch p {
+	case DNS:
+		return "dns"
+	case Connect:
+		return "connect"
+	case TLS:
+		return "tls"
+	case TTFB:
+		return "ttfb"
+	case Transfer:
+		return "transfer"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats aggregates the durations observed for a single phase.
+type Stats struct {
+	Count int
+	Total time.Duration
+	Min   time.Duration
+	Max   time.Duration
+}
+
+func (s Stats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+func (s *Stats) add(d time.Duration) {
+	if s.Count == 0 || d < s.Min {
+		s.Min = d
+	}
+	if d > s.Max {
+		s.Max = d
+	}
+	s.Count++
+	s.Total += d
+}
+
+// Recorder aggregates the phase timings of every request made with a context
+// returned by WithRecorder. It is safe for concurrent use.
+//
+// Phases that a request skips, such as DNS and connecting on a reused
+// connection, are not counted for that request.
+type Recorder struct {
+	mu     sync.Mutex
+	phases [numPhases]Stats
+}
+
+// Stats returns the aggregate timings of a phase so far.
+func (r *Recorder) Stats(p Phase) Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.phases[p]
+}
+
+// Summary returns the aggregate timings of every phase so far.
+func (r *Recorder) Summary() map[Phase]Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := make(map[Phase]Stats, numPhases)
+	for _, p := range Phases {
+		m[p] = r.phases[p]
+	}
+	return m
+}
+
+func (r *Recorder) record(t *tracker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for p := range t.phases {
+		if t.seen[p] {
+			r.phases[p].add(t.phases[p])
+		}
+	}
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a copy of ctx that makes the speedtest.net and fast.com
+// clients record request timings into r.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the recorder carried by ctx, if any.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Track prepares a request context for timing. If ctx carries a Recorder, the
+// returned context has a client trace installed and finish must be called with
+// the request's response, or nil if the request failed. The timings are
+// recorded once the response body has been closed.
+func Track(ctx context.Context) (_ context.Context, finish func(*http.Response)) {
+	r := FromContext(ctx)
+	if r == nil {
+		return ctx, func(*http.Response) {}
+	}
+
+	t := &tracker{}
+	ctx = httptrace.WithClientTrace(ctx, t.clientTrace())
+	return ctx, func(res *http.Response) {
+		if res == nil || res.Body == nil {
+			return
+		}
+		res.Body = &trackedBody{ReadCloser: res.Body, done: func() {
+			t.end()
+			r.record(t)
+		}}
+	}
+}
+
+// Collects the phase timings of a single request. The trace hooks may be
+// called from different goroutines, e.g. when racing IPv4 and IPv6 dials.
+type tracker struct {
+	mu     sync.Mutex
+	starts [numPhases]time.Time
+	phases [numPhases]time.Duration
+	seen   [numPhases]bool
+}
+
+func (t *tracker) start(p Phase) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.starts[p].IsZero() {
+		t.starts[p] = time.Now()
+	}
+}
+
+func (t *tracker) done(p Phase, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil || t.starts[p].IsZero() {
+		return
+	}
+	t.phases[p] = time.Since(t.starts[p])
+	t.seen[p] = true
+}
+
+func (t *tracker) end() {
+	t.done(Transfer, nil)
+}
+
+func (t *tracker) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.start(DNS) },
+		DNSDone: func(i httptrace.DNSDoneInfo) {
+			t.done(DNS, i.Err)
+		},
+		ConnectStart: func(string, string) { t.start(Connect) },
+		ConnectDone: func(_, _ string, err error) {
+			t.done(Connect, err)
+		},
+		TLSHandshakeStart: func() { t.start(TLS) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.done(TLS, err)
+		},
+		WroteRequest: func(i httptrace.WroteRequestInfo) {
+			if i.Err == nil {
+				t.start(TTFB)
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.done(TTFB, nil)
+			t.start(Transfer)
+		},
+	}
+}
+
+// Finishes tracking once the body is closed, which both clients do after
+// reading it to the end.
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,75 @@
+package timing
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrack_NoRecorder(t *testing.T) {
+	ctx := context.Background()
+	if tctx, _ := Track(ctx); tctx != ctx {
+		t.Error("context was modified without a recorder")
+	}
+}
+
+func TestTrack_Phases(t *testing.T) {
+	const (
+		requests = 3
+		delay    = 10 * time.Millisecond
+	)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte("test=test"))
+	}))
+	defer ts.Close()
+
+	var rec Recorder
+	ctx := WithRecorder(context.Background(), &rec)
+	for i := 0; i < requests; i++ {
+		tctx, finish := Track(ctx)
+		req, err := http.NewRequestWithContext(tctx, http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		finish(res)
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	// The connection is reused, so it's only set up once.
+	for _, p := range []Phase{Connect, TLS} {
+		if s := rec.Stats(p); s.Count != 1 {
+			t.Errorf("%v: expected 1 sample, got %d", p, s.Count)
+		}
+	}
+	if s := rec.Stats(TTFB); s.Count != requests || s.Min < delay {
+		t.Errorf("ttfb: got %+v", s)
+	}
+	if s := rec.Stats(Transfer); s.Count != requests {
+		t.Errorf("transfer: got %+v", s)
+	}
+	if s := rec.Stats(DNS); s.Count != 0 {
+		t.Errorf("dns: expected no lookups for an IP, got %+v", s)
+	}
+}
+
+func TestStats_Mean(t *testing.T) {
+	var s Stats
+	if s.Mean() != 0 {
+		t.Fail()
+	}
+	s.add(1 * time.Second)
+	s.add(3 * time.Second)
+	if s.Mean() != 2*time.Second || s.Min != time.Second || s.Max != 3*time.Second {
+		t.Errorf("got: %+v", s)
+	}
+}
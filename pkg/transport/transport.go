@@ -0,0 +1,155 @@
+// Package transport builds the HTTP transports shared by the speedtest.net and
+// fast.com clients.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Options configures how the clients reach the network.
+type Options struct {
+	// Source is the local address connections originate from. It is either an
+	// IP address or the name of a network interface, in which case the
+	// interface's first usable address is used. Empty means the system
+	// default.
+	Source string
+
+	// Proxy is the URL of the proxy every request goes through. The scheme
+	// selects the protocol: "http" or "https" for HTTP CONNECT proxies and
+	// "socks5" for SOCKS5. Credentials are taken from the URL's user info.
+	// Empty means the proxy configured by the environment, if any.
+	Proxy string
+}
+
+// NewClient returns an HTTP client configured according to the options.
+func (o Options) NewClient() (*http.Client, error) {
+	t, err := o.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t}, nil
+}
+
+// NewTransport returns a copy of http.DefaultTransport configured according to
+// the options.
+func (o Options) NewTransport() (*http.Transport, error) {
+	dial, err := o.dialContext()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = dial
+	if o.Proxy != "" {
+		u, err := ParseProxy(o.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	return t, nil
+}
+
+// ParseProxy parses and validates a proxy URL as accepted by Options.Proxy.
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("transport: invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("transport: unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("transport: proxy URL %q has no host", u.Redacted())
+	}
+	return u, nil
+}
+
+// ProxyFor reports the proxy, with any password redacted, that a request to
+// target would go through. It returns an empty string for direct connections.
+func (o Options) ProxyFor(target string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("transport: invalid target URL: %w", err)
+	}
+
+	var u *url.URL
+	if o.Proxy != "" {
+		u, err = ParseProxy(o.Proxy)
+	} else {
+		u, err = http.ProxyFromEnvironment(req)
+	}
+	if err != nil || u == nil {
+		return "", err
+	}
+	return u.Redacted(), nil
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (o Options) dialContext() (dialFunc, error) {
+	d := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if o.Source == "" {
+		return d.DialContext, nil
+	}
+
+	ip, err := sourceIP(o.Source)
+	if err != nil {
+		return nil, err
+	}
+	d.LocalAddr = &net.TCPAddr{IP: ip}
+
+	// A bound socket can only reach destinations of its own address family,
+	// so don't let the dialer try the other one.
+	family := "tcp6"
+	if ip.To4() != nil {
+		family = "tcp4"
+	}
+	return func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return d.DialContext(ctx, family, addr)
+	}, nil
+}
+
+// Resolves a source address or interface name to a local IP, preferring IPv4
+// and skipping link-local addresses which can't route to the internet.
+func sourceIP(source string) (net.IP, error) {
+	if ip := net.ParseIP(source); ip != nil {
+		return ip, nil
+	}
+
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, fmt.Errorf("transport: %q is neither an IP address nor an interface: %w", source, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("transport: could not list addresses of %q: %w", source, err)
+	}
+
+	var v6 net.IP
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || n.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if n.IP.To4() != nil {
+			return n.IP, nil
+		}
+		if v6 == nil {
+			v6 = n.IP
+		}
+	}
+	if v6 == nil {
+		return nil, fmt.Errorf("transport: interface %q has no usable address", source)
+	}
+	return v6, nil
+}